@@ -0,0 +1,200 @@
+// This file provides a means of deciding, based on a sample of a program's own
+// strings, whether those strings are best represented as strings, Eqs, or
+// LGEs.
+
+package intern
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// A Representation is one of the ways a program can represent strings that it
+// needs to compare.
+type Representation int
+
+// These constants represent the various representations that Advise
+// considers.
+const (
+	RepString Representation = iota // Ordinary Go strings
+	RepEq                           // Strings interned to Eqs
+	RepLGE                          // Strings interned to LGEs
+)
+
+// String returns the name of a Representation.
+func (r Representation) String() string {
+	switch r {
+	case RepString:
+		return "string"
+	case RepEq:
+		return "Eq"
+	case RepLGE:
+		return "LGE"
+	default:
+		return "unknown"
+	}
+}
+
+// A Cost reports the measured expense of a single Representation.
+type Cost struct {
+	Rep       Representation // Representation that was measured
+	AllocNs   float64        // Nanoseconds to allocate one symbol
+	CompareNs float64        // Nanoseconds to perform one comparison
+	TotalNs   float64        // Nanoseconds per allocation including its share of comparisons
+}
+
+// Advice is the result of measuring each Representation on a sample of
+// strings.
+type Advice struct {
+	Costs  []Cost         // Cost of each Representation, cheapest first
+	Best   Representation // Cheapest Representation
+	Margin float64        // Ratio of the runner-up's total cost to the best's, or 0 if the best's is 0
+}
+
+// adviseMinTime is the minimum amount of time to spend on each measurement.
+const adviseMinTime = 20 * time.Millisecond
+
+// adviseComp is the number of strings to compare all the others to when
+// measuring comparison costs.  It mirrors the nComp constant used by the
+// package's benchmarks.
+const adviseComp = 1000
+
+// adviseSink is used to prevent measurement loops from being treated as dead
+// code.
+var adviseSink uint64
+
+// measure repeatedly invokes a function until at least adviseMinTime has
+// elapsed.  It returns the average number of nanoseconds per operation, given
+// the number of operations each invocation performs.
+func measure(opsPerCall int, f func()) float64 {
+	var reps int
+	start := time.Now()
+	var elapsed time.Duration
+	for elapsed < adviseMinTime {
+		f()
+		reps++
+		elapsed = time.Since(start)
+	}
+	return float64(elapsed.Nanoseconds()) / float64(reps*opsPerCall)
+}
+
+// Advise measures, on the current machine, the cost of allocating and
+// comparing a sample of strings as strings, as Eqs, and as LGEs.  Given the
+// number of comparisons a program expects to perform per allocation, it
+// reports which representation is cheapest and by how much.  Comparisons are
+// performed the same way as in the package's benchmarks: each string is
+// compared to each of the first 1000 strings in the sample.  Note that only
+// strings and LGEs support ordered comparisons; Advise does not know whether
+// the program needs them.
+//
+// Advise uses private symbol tables and therefore does not disturb any Eqs or
+// LGEs the program has already allocated.  It returns an error if the sample
+// is empty or if comparisonsPerAlloc is negative, infinite, or NaN.
+func Advise(sample []string, comparisonsPerAlloc float64) (Advice, error) {
+	n := len(sample)
+	if n == 0 {
+		e := &PkgError{
+			Code: ErrNoSample,
+			msg:  "Advise requires at least one sample string",
+		}
+		return Advice{}, e
+	}
+	if comparisonsPerAlloc < 0 || math.IsInf(comparisonsPerAlloc, 0) || math.IsNaN(comparisonsPerAlloc) {
+		e := &PkgError{
+			Code: ErrOutOfRange,
+			msg:  fmt.Sprintf("Advise requires a non-negative, finite number of comparisons per allocation, not %g", comparisonsPerAlloc),
+		}
+		return Advice{}, e
+	}
+	nc := n
+	if nc > adviseComp {
+		nc = adviseComp
+	}
+	nCmp := n * nc
+
+	// Measure the cost of string comparisons.  Strings require no
+	// allocation.
+	costs := make([]Cost, 0, 3)
+	var c Cost
+	c.Rep = RepString
+	c.CompareNs = measure(nCmp, func() {
+		for _, s1 := range sample {
+			for _, s2 := range sample[:nc] {
+				if s1 == s2 {
+					adviseSink++
+				}
+			}
+		}
+	})
+	costs = append(costs, c)
+
+	// Measure the cost of Eq allocations and comparisons.
+	var st state
+	eqs := make([]symbol, n)
+	c = Cost{Rep: RepEq}
+	c.AllocNs = measure(n, func() {
+		st.forgetAll()
+		for i, s := range sample {
+			eqs[i], _ = st.assignEq(s)
+		}
+	})
+	c.CompareNs = measure(nCmp, func() {
+		for _, s1 := range eqs {
+			for _, s2 := range eqs[:nc] {
+				if s1 == s2 {
+					adviseSink++
+				}
+			}
+		}
+	})
+	costs = append(costs, c)
+
+	// Measure the cost of LGE allocations and comparisons.  Allocation
+	// follows the recommended practice of pre-allocating all strings
+	// before assigning any of them a symbol.
+	lges := make([]symbol, n)
+	c = Cost{Rep: RepLGE}
+	var err error
+	c.AllocNs = measure(n, func() {
+		if err != nil {
+			return
+		}
+		st.forgetAll()
+		st.pending = append(st.pending, sample...)
+		err = st.flushPending()
+	})
+	if err != nil {
+		return Advice{}, err
+	}
+	for i, s := range sample {
+		lges[i] = st.getSymbol(s)
+	}
+	c.CompareNs = measure(nCmp, func() {
+		for _, s1 := range lges {
+			for _, s2 := range lges[:nc] {
+				if s1 < s2 {
+					adviseSink++
+				}
+			}
+		}
+	})
+	costs = append(costs, c)
+
+	// Rank the representations by total cost.
+	for i := range costs {
+		costs[i].TotalNs = costs[i].AllocNs + comparisonsPerAlloc*costs[i].CompareNs
+	}
+	sort.SliceStable(costs, func(i, j int) bool {
+		return costs[i].TotalNs < costs[j].TotalNs
+	})
+	adv := Advice{
+		Costs: costs,
+		Best:  costs[0].Rep,
+	}
+	if costs[0].TotalNs > 0 {
+		adv.Margin = costs[1].TotalNs / costs[0].TotalNs
+	}
+	return adv, nil
+}
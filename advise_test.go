@@ -0,0 +1,77 @@
+// This file provides unit tests for the Advise function.
+
+package intern_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// TestAdvise ensures that Advise ranks all representations consistently.
+func TestAdvise(t *testing.T) {
+	adv, err := intern.Advise(ozChars, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(adv.Costs) != 3 {
+		t.Fatalf("Expected 3 costs but saw %d", len(adv.Costs))
+	}
+	if adv.Best != adv.Costs[0].Rep {
+		t.Fatalf("Best representation is %s but the cheapest is %s", adv.Best, adv.Costs[0].Rep)
+	}
+	for i := 1; i < len(adv.Costs); i++ {
+		if adv.Costs[i].TotalNs < adv.Costs[i-1].TotalNs {
+			t.Fatalf("Costs are not sorted: %v", adv.Costs)
+		}
+	}
+	if adv.Margin < 1.0 {
+		t.Fatalf("Expected a margin of at least 1.0 but saw %.2f", adv.Margin)
+	}
+}
+
+// TestAdviseGlobal ensures that Advise does not disturb existing symbols.
+func TestAdviseGlobal(t *testing.T) {
+	intern.ForgetAllEqs()
+	sym := intern.NewEq(ozChars[0])
+	_, err := intern.Advise(ozChars, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sym.String() != ozChars[0] {
+		t.Fatalf("Expected %q but saw %q", ozChars[0], sym)
+	}
+	if intern.NewEq(ozChars[1]) != sym+1 {
+		t.Fatal("Advise allocated Eqs in the global symbol table")
+	}
+}
+
+// TestAdviseEmpty ensures that Advise rejects an empty sample.
+func TestAdviseEmpty(t *testing.T) {
+	_, err := intern.Advise(nil, 1)
+	if e, ok := err.(*intern.PkgError); !ok || e.Code != intern.ErrNoSample {
+		t.Fatalf("Expected ErrNoSample but saw %v", err)
+	}
+}
+
+// TestAdviseRatio ensures that Advise rejects invalid comparison ratios and
+// reports a finite margin when no comparisons are performed.
+func TestAdviseRatio(t *testing.T) {
+	for _, r := range []float64{-1, math.Inf(1), math.NaN()} {
+		_, err := intern.Advise(ozChars, r)
+		if e, ok := err.(*intern.PkgError); !ok || e.Code != intern.ErrOutOfRange {
+			t.Fatalf("Expected ErrOutOfRange for %g but saw %v", r, err)
+		}
+	}
+	adv, err := intern.Advise(ozChars, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adv.Best != intern.RepString {
+		t.Fatalf("Expected strings to be best but saw %s", adv.Best)
+	}
+	if math.IsInf(adv.Margin, 0) || math.IsNaN(adv.Margin) {
+		t.Fatalf("Expected a finite margin but saw %g", adv.Margin)
+	}
+}
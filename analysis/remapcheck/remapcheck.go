@@ -0,0 +1,347 @@
+// Package remapcheck defines an Analyzer that reports LGEs that may outlive
+// the mapping that gave them meaning.
+//
+// intern.RemapAllLGEs reassigns every LGE and returns a map from old LGEs to
+// new LGEs.  intern.ForgetAllLGEs discards every LGE outright.  Either way,
+// LGEs stored in long-lived locations silently become wrong unless the program
+// updates them.  remapcheck reports
+//
+//   - calls to intern.RemapAllLGEs whose returned map is discarded, and
+//   - in packages that call intern.RemapAllLGEs or intern.ForgetAllLGEs,
+//     struct fields and global variables that hold LGEs but are never
+//     updated.
+//
+// A field or global variable is considered updated if the package assigns to
+// it an expression that indexes (or ranges over) a map[intern.LGE]intern.LGE,
+// as returned by intern.RemapAllLGEs.  In packages that call
+// intern.ForgetAllLGEs but not intern.RemapAllLGEs, assigning the result of
+// intern.NewLGE or intern.NewLGEMulti also counts as an update.
+//
+// These checks are purely syntactic heuristics.  Package intern provides no
+// way to register an LGE holder so that it is updated on remapping, and
+// remapcheck does not track whether an update actually runs after each
+// remapping, whether it covers every element of a slice or map, or whether
+// LGEs escape into local variables that outlive the remapping.  A clean
+// report therefore does not prove that a program updates all of its LGEs.
+package remapcheck
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// internPath is the import path of the package whose symbols we check.
+const internPath = "github.com/spakin/intern"
+
+// Doc describes the analyzer.
+const Doc = `check for intern.LGEs that are not updated after remapping
+
+The remapcheck analyzer reports calls to intern.RemapAllLGEs whose result is
+discarded and, in packages that call intern.RemapAllLGEs or
+intern.ForgetAllLGEs, struct fields and global variables of type intern.LGE
+that the package never updates.  The checks are heuristic: a field or
+variable counts as updated if the package ever assigns it a value derived
+from a remapping, whether or not that assignment runs after every remapping.`
+
+// Analyzer reports LGEs that are not updated after remapping.
+var Analyzer = &analysis.Analyzer{
+	Name:     "remapcheck",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// isLGE says if a type is intern.LGE.
+func isLGE(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := n.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == internPath && obj.Name() == "LGE"
+}
+
+// holdsLGE says if a value of a given type holds one or more LGEs, either
+// directly or in a pointer, slice, array, map, or channel.
+func holdsLGE(t types.Type) bool {
+	if isLGE(t) {
+		return true
+	}
+	switch t := t.(type) {
+	case *types.Pointer:
+		return holdsLGE(t.Elem())
+	case *types.Slice:
+		return holdsLGE(t.Elem())
+	case *types.Array:
+		return holdsLGE(t.Elem())
+	case *types.Chan:
+		return holdsLGE(t.Elem())
+	case *types.Map:
+		return holdsLGE(t.Key()) || holdsLGE(t.Elem())
+	default:
+		return false
+	}
+}
+
+// isRemapMap says if a type is map[intern.LGE]intern.LGE.
+func isRemapMap(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	m, ok := t.Underlying().(*types.Map)
+	return ok && isLGE(m.Key()) && isLGE(m.Elem())
+}
+
+// internFunc returns the name of the package-intern function a call invokes
+// or the empty string if the call is to any other function.
+func internFunc(info *types.Info, call *ast.CallExpr) string {
+	var id *ast.Ident
+	switch fn := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fn
+	case *ast.SelectorExpr:
+		id = fn.Sel
+	default:
+		return ""
+	}
+	f, ok := info.Uses[id].(*types.Func)
+	if !ok || f.Pkg() == nil || f.Pkg().Path() != internPath {
+		return ""
+	}
+	return f.Name()
+}
+
+// target returns the field or package-level variable that an assignment to a
+// given expression ultimately modifies, or nil if there is none.
+func target(info *types.Info, e ast.Expr) *types.Var {
+	for {
+		switch x := e.(type) {
+		case *ast.ParenExpr:
+			e = x.X
+		case *ast.StarExpr:
+			e = x.X
+		case *ast.IndexExpr:
+			e = x.X
+		case *ast.SelectorExpr:
+			v, _ := info.Uses[x.Sel].(*types.Var)
+			return v
+		case *ast.Ident:
+			v, _ := info.ObjectOf(x).(*types.Var)
+			return v
+		default:
+			return nil
+		}
+	}
+}
+
+// checker holds the state needed to analyze a single package.
+type checker struct {
+	pass        *analysis.Pass
+	callsRemap  bool                    // Package calls RemapAllLGEs
+	callsForget bool                    // Package calls ForgetAllLGEs
+	rangeVars   map[types.Object]bool   // Variables defined by ranging over a remap map
+	updated     map[*types.Var]bool     // Fields and globals that are updated
+	viaNew      map[*types.Var]bool     // Fields and globals assigned from NewLGE
+	candidates  []*types.Var            // Fields and globals that hold LGEs
+	declared    map[*types.Var]ast.Node // Declaration of each candidate
+}
+
+// run reports LGEs that are not updated after remapping within a single
+// package.
+func run(pass *analysis.Pass) (interface{}, error) {
+	if pass.Pkg.Path() == internPath {
+		return nil, nil
+	}
+	c := &checker{
+		pass:      pass,
+		rangeVars: make(map[types.Object]bool),
+		updated:   make(map[*types.Var]bool),
+		viaNew:    make(map[*types.Var]bool),
+		declared:  make(map[*types.Var]ast.Node),
+	}
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	// Find calls to RemapAllLGEs and ForgetAllLGEs, and note all
+	// variables that range over a remap map.
+	insp.Preorder([]ast.Node{
+		(*ast.CallExpr)(nil),
+		(*ast.RangeStmt)(nil),
+	}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			switch internFunc(pass.TypesInfo, n) {
+			case "RemapAllLGEs":
+				c.callsRemap = true
+			case "ForgetAllLGEs":
+				c.callsForget = true
+			}
+		case *ast.RangeStmt:
+			if isRemapMap(pass.TypesInfo.TypeOf(n.X)) {
+				for _, e := range []ast.Expr{n.Key, n.Value} {
+					if id, ok := e.(*ast.Ident); ok {
+						if obj := pass.TypesInfo.ObjectOf(id); obj != nil {
+							c.rangeVars[obj] = true
+						}
+					}
+				}
+			}
+		}
+	})
+
+	// Check each statement that discards a remap map, and record each
+	// assignment that updates a field or global.
+	insp.Preorder([]ast.Node{
+		(*ast.ExprStmt)(nil),
+		(*ast.AssignStmt)(nil),
+		(*ast.ValueSpec)(nil),
+	}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.ExprStmt:
+			if call, ok := ast.Unparen(n.X).(*ast.CallExpr); ok && internFunc(pass.TypesInfo, call) == "RemapAllLGEs" {
+				pass.Reportf(call.Pos(), "result of intern.RemapAllLGEs is discarded; stored LGEs will not be updated")
+			}
+		case *ast.AssignStmt:
+			c.checkDiscard(n.Lhs, n.Rhs)
+			c.recordUpdates(n.Lhs, n.Rhs)
+		case *ast.ValueSpec:
+			lhs := make([]ast.Expr, len(n.Names))
+			for i, id := range n.Names {
+				lhs[i] = id
+			}
+			c.checkDiscard(lhs, n.Values)
+		}
+	})
+
+	// Report fields and globals that are never updated.
+	if !c.callsRemap && !c.callsForget {
+		return nil, nil
+	}
+	c.findCandidates()
+	for _, v := range c.candidates {
+		if c.updated[v] || (!c.callsRemap && c.viaNew[v]) {
+			continue
+		}
+		kind := "global variable"
+		if v.IsField() {
+			kind = "field"
+		}
+		fn := "intern.RemapAllLGEs"
+		if !c.callsRemap {
+			fn = "intern.ForgetAllLGEs"
+		}
+		pass.Reportf(c.declared[v].Pos(), "%s %s holds LGEs but is never updated after %s", kind, v.Name(), fn)
+	}
+	return nil, nil
+}
+
+// checkDiscard reports an assignment of the result of RemapAllLGEs to the
+// blank identifier.
+func (c *checker) checkDiscard(lhs, rhs []ast.Expr) {
+	if len(rhs) != 1 || len(lhs) == 0 {
+		return
+	}
+	call, ok := ast.Unparen(rhs[0]).(*ast.CallExpr)
+	if !ok || internFunc(c.pass.TypesInfo, call) != "RemapAllLGEs" {
+		return
+	}
+	if id, ok := lhs[0].(*ast.Ident); ok && id.Name == "_" {
+		c.pass.Reportf(call.Pos(), "result of intern.RemapAllLGEs is discarded; stored LGEs will not be updated")
+	}
+}
+
+// recordUpdates records the fields and globals that an assignment updates.
+func (c *checker) recordUpdates(lhs, rhs []ast.Expr) {
+	for i, l := range lhs {
+		v := target(c.pass.TypesInfo, l)
+		if v == nil {
+			continue
+		}
+		var r ast.Expr
+		switch {
+		case len(lhs) == len(rhs):
+			r = rhs[i]
+		case len(rhs) == 1:
+			r = rhs[0]
+		default:
+			continue
+		}
+		remapped, interned := c.classify(r)
+		if remapped {
+			c.updated[v] = true
+		}
+		if interned {
+			c.viaNew[v] = true
+		}
+	}
+}
+
+// classify says if an expression draws on a remap map and if it draws on a
+// call to NewLGE or NewLGEMulti.
+func (c *checker) classify(e ast.Expr) (remapped, interned bool) {
+	info := c.pass.TypesInfo
+	ast.Inspect(e, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IndexExpr:
+			if isRemapMap(info.TypeOf(n.X)) {
+				remapped = true
+			}
+		case *ast.Ident:
+			if obj := info.ObjectOf(n); obj != nil && c.rangeVars[obj] {
+				remapped = true
+			}
+		case *ast.CallExpr:
+			switch internFunc(info, n) {
+			case "NewLGE", "NewLGEMulti":
+				interned = true
+			}
+		}
+		return true
+	})
+	return
+}
+
+// findCandidates finds all struct fields and package-level variables declared
+// in the package that hold LGEs.
+func (c *checker) findCandidates() {
+	info := c.pass.TypesInfo
+	add := func(id *ast.Ident) {
+		v, ok := info.Defs[id].(*types.Var)
+		if !ok || !holdsLGE(v.Type()) {
+			return
+		}
+		c.candidates = append(c.candidates, v)
+		c.declared[v] = id
+	}
+	for _, f := range c.pass.Files {
+		// Consider package-level variables.
+		for _, d := range f.Decls {
+			gd, ok := d.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				if vs, ok := spec.(*ast.ValueSpec); ok {
+					for _, id := range vs.Names {
+						add(id)
+					}
+				}
+			}
+		}
+
+		// Consider fields of all struct types.
+		ast.Inspect(f, func(n ast.Node) bool {
+			if st, ok := n.(*ast.StructType); ok {
+				for _, fld := range st.Fields.List {
+					for _, id := range fld.Names {
+						add(id)
+					}
+				}
+			}
+			return true
+		})
+	}
+}
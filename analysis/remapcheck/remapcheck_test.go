@@ -0,0 +1,15 @@
+// This file provides unit tests for the remapcheck analyzer.
+
+package remapcheck_test
+
+import (
+	"testing"
+
+	"github.com/spakin/intern/analysis/remapcheck"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// TestRemapcheck runs the analyzer on the test packages in testdata/src.
+func TestRemapcheck(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), remapcheck.Analyzer, "a", "b", "c")
+}
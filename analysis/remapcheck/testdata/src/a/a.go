@@ -0,0 +1,39 @@
+// Package a calls RemapAllLGEs.
+package a
+
+import "github.com/spakin/intern"
+
+var stale intern.LGE // want `global variable stale holds LGEs but is never updated after intern.RemapAllLGEs`
+
+var fresh []intern.LGE
+
+type record struct {
+	name  string
+	key   intern.LGE            // want `field key holds LGEs but is never updated after intern.RemapAllLGEs`
+	keys  map[intern.LGE]string // want `field keys holds LGEs but is never updated after intern.RemapAllLGEs`
+	label intern.LGE
+	next  *record
+}
+
+func discard() {
+	intern.RemapAllLGEs()              // want `result of intern.RemapAllLGEs is discarded`
+	_, _ = intern.RemapAllLGEs()       // want `result of intern.RemapAllLGEs is discarded`
+	var _, err = intern.RemapAllLGEs() // want `result of intern.RemapAllLGEs is discarded`
+	_ = err
+}
+
+func update(r *record) error {
+	m, err := intern.RemapAllLGEs()
+	if err != nil {
+		return err
+	}
+	r.label = m[r.label]
+	for i, s := range fresh {
+		fresh[i] = m[s]
+	}
+	return nil
+}
+
+func reintern(r *record) {
+	r.key, _ = intern.NewLGE(r.name)
+}
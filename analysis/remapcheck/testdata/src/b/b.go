@@ -0,0 +1,15 @@
+// Package b never remaps LGEs, so it may store them freely.
+package b
+
+import "github.com/spakin/intern"
+
+var global intern.LGE
+
+type record struct {
+	key intern.LGE
+}
+
+func make(s string) record {
+	k, _ := intern.NewLGE(s)
+	return record{key: k}
+}
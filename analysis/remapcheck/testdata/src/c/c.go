@@ -0,0 +1,17 @@
+// Package c calls ForgetAllLGEs but not RemapAllLGEs.
+package c
+
+import "github.com/spakin/intern"
+
+type queue struct {
+	strs []string
+	syms []intern.LGE
+	top  intern.LGE // want `field top holds LGEs but is never updated after intern.ForgetAllLGEs`
+}
+
+func (q *queue) reset() error {
+	intern.ForgetAllLGEs()
+	var err error
+	q.syms, err = intern.NewLGEMulti(q.strs)
+	return err
+}
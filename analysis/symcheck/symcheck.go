@@ -0,0 +1,269 @@
+// Package symcheck defines an Analyzer that reports misuse of the symbol types
+// provided by package intern.
+//
+// An intern.Eq (or its compact variant, intern.Eq32) supports only equality
+// and inequality comparisons, but nothing in the Go type system prevents a
+// program from writing a < b for two Eqs.
+// Likewise, the integer values of Eqs and LGEs are assigned by package intern
+// and have no meaning on their own, so arithmetic on symbols and conversions
+// from arbitrary integers to symbols almost certainly indicate a bug.
+// symcheck reports
+//
+//   - ordered comparisons (<, <=, >, >=) of Eqs,
+//   - sorting, minimizing, or maximizing Eqs using the standard library
+//     (slices.Sort, sort.Sort, cmp.Compare, min, max, etc.),
+//   - arithmetic and bitwise operations on Eqs and LGEs, and
+//   - conversions to Eq, Eq32, or LGE from any value other than another
+//     symbol or the constant 0, and
+//   - conversions from Eq to Eq32, which may silently truncate the Eq.
+package symcheck
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// internPath is the import path of the package whose symbols we check.
+const internPath = "github.com/spakin/intern"
+
+// Doc describes the analyzer.
+const Doc = `check for misuse of intern.Eq, intern.Eq32, and intern.LGE symbols
+
+The symcheck analyzer reports ordered comparisons and sorting of intern.Eq
+and intern.Eq32 values, arithmetic on symbols, conversions from arbitrary
+integers to symbols, and truncating conversions from intern.Eq to
+intern.Eq32.`
+
+// Analyzer reports misuse of intern.Eq, intern.Eq32, and intern.LGE symbols.
+var Analyzer = &analysis.Analyzer{
+	Name:     "symcheck",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// symbolName returns "Eq", "Eq32", or "LGE" if the given type is intern.Eq,
+// intern.Eq32, or intern.LGE, respectively, and the empty string otherwise.
+func symbolName(t types.Type) string {
+	n, ok := t.(*types.Named)
+	if !ok {
+		return ""
+	}
+	obj := n.Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path() != internPath {
+		return ""
+	}
+	switch obj.Name() {
+	case "Eq", "Eq32", "LGE":
+		return obj.Name()
+	default:
+		return ""
+	}
+}
+
+// exprSymbol returns the name of the symbol type of an expression or the
+// empty string if the expression is not a symbol.
+func exprSymbol(info *types.Info, e ast.Expr) string {
+	t := info.TypeOf(e)
+	if t == nil {
+		return ""
+	}
+	return symbolName(t)
+}
+
+// elemSymbol returns the name of the symbol type of the elements of a slice
+// expression or the empty string if the elements are not symbols.
+func elemSymbol(info *types.Info, e ast.Expr) string {
+	t := info.TypeOf(e)
+	if t == nil {
+		return ""
+	}
+	s, ok := t.Underlying().(*types.Slice)
+	if !ok {
+		return ""
+	}
+	return symbolName(s.Elem())
+}
+
+// unordered says if a symbol type supports only equality comparisons.
+func unordered(sym string) bool {
+	return sym == "Eq" || sym == "Eq32"
+}
+
+// orderedFuncs lists the standard-library functions that impose an order on
+// their arguments.  Each function is mapped to a Boolean that indicates
+// whether it takes a slice (true) or individual values (false).  The sort
+// package's functions take a sort.Interface or an arbitrary slice, so they
+// are reported whenever the argument's underlying type is a slice of symbols.
+var orderedFuncs = map[string]map[string]bool{
+	"slices": {
+		"BinarySearch": true,
+		"IsSorted":     true,
+		"Max":          true,
+		"Min":          true,
+		"Sort":         true,
+	},
+	"sort": {
+		"IsSorted":      true,
+		"Slice":         true,
+		"SliceIsSorted": true,
+		"SliceStable":   true,
+		"Sort":          true,
+		"Stable":        true,
+	},
+	"cmp": {
+		"Compare": false,
+		"Less":    false,
+	},
+}
+
+// isArith says if a token represents an arithmetic or bitwise operator.
+func isArith(op token.Token) bool {
+	switch op {
+	case token.ADD, token.SUB, token.MUL, token.QUO, token.REM,
+		token.AND, token.OR, token.XOR, token.SHL, token.SHR, token.AND_NOT,
+		token.ADD_ASSIGN, token.SUB_ASSIGN, token.MUL_ASSIGN, token.QUO_ASSIGN,
+		token.REM_ASSIGN, token.AND_ASSIGN, token.OR_ASSIGN, token.XOR_ASSIGN,
+		token.SHL_ASSIGN, token.SHR_ASSIGN, token.AND_NOT_ASSIGN,
+		token.INC, token.DEC:
+		return true
+	default:
+		return false
+	}
+}
+
+// isOrdered says if a token represents an ordered comparison.
+func isOrdered(op token.Token) bool {
+	switch op {
+	case token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return true
+	default:
+		return false
+	}
+}
+
+// run reports misuse of symbols within a single package.
+func run(pass *analysis.Pass) (interface{}, error) {
+	// Package intern itself is allowed to do anything it wants with
+	// symbols.
+	if pass.Pkg.Path() == internPath {
+		return nil, nil
+	}
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nodeFilter := []ast.Node{
+		(*ast.BinaryExpr)(nil),
+		(*ast.UnaryExpr)(nil),
+		(*ast.AssignStmt)(nil),
+		(*ast.IncDecStmt)(nil),
+		(*ast.CallExpr)(nil),
+	}
+	insp.Preorder(nodeFilter, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.BinaryExpr:
+			checkBinary(pass, n)
+		case *ast.UnaryExpr:
+			if n.Op == token.SUB || n.Op == token.XOR {
+				if sym := exprSymbol(pass.TypesInfo, n.X); sym != "" {
+					pass.Reportf(n.Pos(), "arithmetic on intern.%s value", sym)
+				}
+			}
+		case *ast.AssignStmt:
+			if isArith(n.Tok) && len(n.Lhs) == 1 {
+				if sym := exprSymbol(pass.TypesInfo, n.Lhs[0]); sym != "" {
+					pass.Reportf(n.Pos(), "arithmetic on intern.%s value", sym)
+				}
+			}
+		case *ast.IncDecStmt:
+			if sym := exprSymbol(pass.TypesInfo, n.X); sym != "" {
+				pass.Reportf(n.Pos(), "arithmetic on intern.%s value", sym)
+			}
+		case *ast.CallExpr:
+			checkCall(pass, n)
+		}
+	})
+	return nil, nil
+}
+
+// checkBinary reports ordered comparisons of Eqs and Eq32s and arithmetic on
+// symbols.
+func checkBinary(pass *analysis.Pass, e *ast.BinaryExpr) {
+	sym := exprSymbol(pass.TypesInfo, e.X)
+	if sym == "" {
+		sym = exprSymbol(pass.TypesInfo, e.Y)
+	}
+	switch {
+	case sym == "":
+	case unordered(sym) && isOrdered(e.Op):
+		pass.Reportf(e.OpPos, "ordered comparison (%s) of intern.%s values; %s supports only == and !=", e.Op, sym, sym)
+	case isArith(e.Op):
+		pass.Reportf(e.OpPos, "arithmetic on intern.%s value", sym)
+	}
+}
+
+// checkCall reports conversions from integers to symbols, conversions from
+// Eqs to Eq32s, and calls that order Eqs or Eq32s.
+func checkCall(pass *analysis.Pass, call *ast.CallExpr) {
+	info := pass.TypesInfo
+
+	// Check for conversions to a symbol type.
+	if tv, ok := info.Types[call.Fun]; ok && tv.IsType() {
+		sym := symbolName(tv.Type)
+		if sym == "" || len(call.Args) != 1 {
+			return
+		}
+		arg := call.Args[0]
+		if av, ok := info.Types[arg]; ok && av.Value != nil {
+			// Constants are typed as the symbol they're converted
+			// to, so check them before checking the argument type.
+			if v, exact := constant.Uint64Val(constant.ToInt(av.Value)); exact && v == 0 {
+				return // The zero symbol is allowed.
+			}
+		} else if from := exprSymbol(info, arg); from != "" {
+			if from == "Eq" && sym == "Eq32" {
+				pass.Reportf(call.Pos(), "conversion of intern.Eq to intern.Eq32 may truncate; use the Eq32 method instead")
+			}
+			return
+		}
+		pass.Reportf(call.Pos(), "conversion of arbitrary value to intern.%s; use intern.New%s instead", sym, sym)
+		return
+	}
+
+	// Check for the min and max builtins applied to Eqs.
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		if b, ok := info.Uses[fn].(*types.Builtin); ok && (b.Name() == "min" || b.Name() == "max") {
+			for _, arg := range call.Args {
+				if sym := exprSymbol(info, arg); unordered(sym) {
+					pass.Reportf(call.Pos(), "%s of intern.%s values; %s supports only == and !=", b.Name(), sym, sym)
+					return
+				}
+			}
+		}
+
+	case *ast.SelectorExpr:
+		// Check for standard-library functions that order Eqs.
+		f, ok := info.Uses[fn.Sel].(*types.Func)
+		if !ok || f.Pkg() == nil || len(call.Args) == 0 {
+			return
+		}
+		isSlice, ok := orderedFuncs[f.Pkg().Path()][f.Name()]
+		if !ok {
+			return
+		}
+		var sym string
+		if isSlice {
+			sym = elemSymbol(info, call.Args[0])
+		} else {
+			sym = exprSymbol(info, call.Args[0])
+		}
+		if unordered(sym) {
+			pass.Reportf(call.Pos(), "%s.%s orders intern.%s values; %s supports only == and !=", f.Pkg().Name(), f.Name(), sym, sym)
+		}
+	}
+}
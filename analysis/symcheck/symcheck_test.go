@@ -0,0 +1,15 @@
+// This file provides unit tests for the symcheck analyzer.
+
+package symcheck_test
+
+import (
+	"testing"
+
+	"github.com/spakin/intern/analysis/symcheck"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// TestSymcheck runs the analyzer on the test package in testdata/src/a.
+func TestSymcheck(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), symcheck.Analyzer, "a")
+}
@@ -0,0 +1,74 @@
+package a
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+
+	"github.com/spakin/intern"
+)
+
+func comparisons(a, b intern.Eq, x, y intern.LGE) bool {
+	_ = a == b
+	_ = a != b
+	_ = a < b  // want `ordered comparison \(<\) of intern.Eq values`
+	_ = a <= b // want `ordered comparison \(<=\) of intern.Eq values`
+	_ = a > b  // want `ordered comparison \(>\) of intern.Eq values`
+	_ = a >= b // want `ordered comparison \(>=\) of intern.Eq values`
+	_ = x < y
+	return x >= y
+}
+
+type eqSlice []intern.Eq
+
+func (es eqSlice) Len() int           { return len(es) }
+func (es eqSlice) Less(i, j int) bool { return es[i] < es[j] } // want `ordered comparison`
+func (es eqSlice) Swap(i, j int)      { es[i], es[j] = es[j], es[i] }
+
+func sorting(es []intern.Eq, ls []intern.LGE) {
+	sort.Sort(eqSlice(es))                                       // want `sort.Sort orders intern.Eq values`
+	sort.Stable(eqSlice(es))                                     // want `sort.Stable orders intern.Eq values`
+	sort.Slice(es, func(i, j int) bool { return es[i] < es[j] }) // want `sort.Slice orders intern.Eq values` `ordered comparison`
+	slices.Sort(es)                                              // want `slices.Sort orders intern.Eq values`
+	_ = slices.Max(es)                                           // want `slices.Max orders intern.Eq values`
+	_ = cmp.Compare(es[0], es[1])                                // want `cmp.Compare orders intern.Eq values`
+	_ = min(es[0], es[1])                                        // want `min of intern.Eq values`
+	slices.Sort(ls)
+	_ = max(ls[0], ls[1])
+}
+
+func arithmetic(a intern.Eq, x intern.LGE) {
+	_ = a + 1  // want `arithmetic on intern.Eq value`
+	_ = x - x  // want `arithmetic on intern.LGE value`
+	_ = x &^ 3 // want `arithmetic on intern.LGE value`
+	_ = -a     // want `arithmetic on intern.Eq value`
+	a++        // want `arithmetic on intern.Eq value`
+	x += 2     // want `arithmetic on intern.LGE value`
+	n := 3
+	n++
+	_ = n * 2
+}
+
+func conversions(n int, u uint64, a intern.Eq) {
+	var zero intern.Eq = intern.Eq(0)
+	_ = zero
+	_ = intern.Eq(a)
+	_ = intern.Eq(n)     // want `conversion of arbitrary value to intern.Eq`
+	_ = intern.LGE(u)    // want `conversion of arbitrary value to intern.LGE`
+	_ = intern.Eq(12345) // want `conversion of arbitrary value to intern.Eq`
+	_ = uint64(a)
+	_ = intern.NewEq("ok")
+}
+
+func eq32s(a, b intern.Eq32, e intern.Eq, es []intern.Eq32) {
+	_ = a == b
+	_ = a < b              // want `ordered comparison \(<\) of intern.Eq32 values`
+	_ = a + 1              // want `arithmetic on intern.Eq32 value`
+	_ = max(a, b)          // want `max of intern.Eq32 values`
+	slices.Sort(es)        // want `slices.Sort orders intern.Eq32 values`
+	_ = intern.Eq32(e)     // want `conversion of intern.Eq to intern.Eq32 may truncate`
+	_ = intern.Eq32(12345) // want `conversion of arbitrary value to intern.Eq32`
+	_ = intern.Eq(a)
+	_ = a.Eq()
+	_, _ = e.Eq32()
+}
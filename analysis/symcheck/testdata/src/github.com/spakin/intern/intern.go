@@ -0,0 +1,19 @@
+// Package intern is a stub of the real intern package for use by the
+// symcheck tests.
+package intern
+
+type symbol uint64
+
+type Eq symbol
+
+type Eq32 uint32
+
+type LGE symbol
+
+func NewEq(s string) Eq { return Eq(len(s) + 1) }
+
+func (s Eq) Eq32() (Eq32, error) { return Eq32(s), nil }
+
+func (s Eq32) Eq() Eq { return Eq(s) }
+
+func NewLGE(s string) (LGE, error) { return LGE(len(s) + 1), nil }
@@ -0,0 +1,89 @@
+/*
+Internadvise reports whether a corpus of strings is best compared as strings,
+as intern.Eq symbols, or as intern.LGE symbols.
+
+Usage:
+
+	internadvise [-c comparisons] [file...]
+
+Internadvise reads strings, one per line, from the named files or, if none are
+named, from standard input.  It then measures the cost of allocating and
+comparing those strings in each representation on the current machine and
+reports which representation is fastest given the expected number of
+comparisons per allocation (-c).
+*/
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spakin/intern"
+)
+
+// readLines appends each line read from an io.Reader to a slice of strings and
+// returns the new slice.
+func readLines(ss []string, r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		ss = append(ss, scanner.Text())
+	}
+	return ss, scanner.Err()
+}
+
+func main() {
+	// Parse the command line.
+	cpa := flag.Float64("c", 10, "expected number of comparisons per allocation")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-c comparisons] [file...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Read the sample strings.
+	var sample []string
+	var err error
+	if flag.NArg() == 0 {
+		sample, err = readLines(sample, os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	for _, fn := range flag.Args() {
+		f, err := os.Open(fn)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		sample, err = readLines(sample, f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	// Measure each representation and report the results.
+	adv, err := intern.Advise(sample, *cpa)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Representation\tAlloc (ns)\tCompare (ns)\tTotal (ns)\t")
+	for _, c := range adv.Costs {
+		fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%.2f\t\n", c.Rep, c.AllocNs, c.CompareNs, c.TotalNs)
+	}
+	tw.Flush()
+	fmt.Printf("\nWith %g comparisons per allocation of %d strings, %s is fastest", *cpa, len(sample), adv.Best)
+	if adv.Margin > 0 {
+		fmt.Printf(" (%.2fx faster than %s)", adv.Margin, adv.Costs[1].Rep)
+	}
+	fmt.Println(".")
+}
@@ -0,0 +1,256 @@
+/*
+Interngen generates Go source code that declares intern.Eq or intern.LGE
+constants for a list of well-known strings.  It is meant to be invoked by go
+generate:
+
+	//go:generate interngen -type Eq -prefix Kw -o keywords_intern.go keywords.txt
+
+Usage:
+
+	interngen [-type Eq|LGE] [-prefix prefix] [-package name] [-base n] [-o file] [file]
+
+Interngen reads strings, one per line, from the named file or, if none is
+named, from standard input.  Blank lines and lines beginning with "#" are
+ignored.  Each constant's name is formed from the prefix followed by the
+string in CamelCase, keeping only letters and digits.  A line of the form
+"Name<TAB>string" names the constant explicitly.
+
+The generated code reserves the constants' values in the Eq or LGE symbol
+table when the package is initialized (see intern.ReserveEqs and
+intern.ReserveLGEs) so that, for example,
+
+	switch intern.NewEq(word) {
+	case KwSelect:
+		...
+	}
+
+works without any run-time lookups of the keyword strings.  Eq constants are
+numbered consecutively from the -base value.  Programs that generate more than
+one set of Eq constants must give each set a non-overlapping range.  LGE
+constants are assigned exactly as intern.NewLGEMulti would assign them in an
+empty symbol table.  The package that declares LGE constants should therefore
+be initialized before any other LGEs are allocated, and the program should not
+call intern.RemapAllLGEs or intern.ForgetAllLGEs.
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spakin/intern"
+)
+
+// A constant represents a single constant to generate.
+type constant struct {
+	name  string // Name of the constant
+	str   string // String the constant represents
+	value uint64 // Value of the constant
+}
+
+// A config represents the parameters that control code generation.
+type config struct {
+	typ    string // "Eq" or "LGE"
+	prefix string // Prefix for each constant name
+	pkg    string // Name of the generated package
+	base   uint64 // First Eq value to assign
+	args   string // Command-line arguments to report in the output
+}
+
+// constName converts a string to a constant name by capitalizing each
+// alphanumeric word and discarding everything else.
+func constName(prefix, s string) string {
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	upper := true
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if upper {
+				r = unicode.ToUpper(r)
+			}
+			sb.WriteRune(r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	return sb.String()
+}
+
+// readConstants reads a list of strings and returns a list of constants, in
+// order of appearance, with their names but not their values.
+func readConstants(r io.Reader, prefix string) ([]constant, error) {
+	var cs []constant
+	names := make(map[string]string)
+	strs := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var c constant
+		if i := strings.IndexByte(line, '\t'); i >= 0 {
+			c.name = prefix + line[:i]
+			c.str = line[i+1:]
+		} else {
+			c.name = constName(prefix, line)
+			c.str = line
+		}
+		switch {
+		case !token.IsIdentifier(c.name):
+			return nil, fmt.Errorf("line %d: %q is not a valid Go identifier", lineNum, c.name)
+		case names[c.name] != "":
+			return nil, fmt.Errorf("line %d: %q and %q both map to %s", lineNum, names[c.name], c.str, c.name)
+		case strs[c.str]:
+			return nil, fmt.Errorf("line %d: duplicate string %q", lineNum, c.str)
+		}
+		names[c.name] = c.str
+		strs[c.str] = true
+		cs = append(cs, c)
+	}
+	return cs, scanner.Err()
+}
+
+// assignValues assigns a value to each constant.
+func assignValues(cfg config, cs []constant) error {
+	switch cfg.typ {
+	case "Eq":
+		for i := range cs {
+			cs[i].value = cfg.base + uint64(i)
+		}
+	case "LGE":
+		// Assign LGEs exactly as an empty symbol table would.
+		strs := make([]string, len(cs))
+		for i, c := range cs {
+			strs[i] = c.str
+		}
+		intern.ForgetAllLGEs()
+		syms, err := intern.NewLGEMulti(strs)
+		if err != nil {
+			return err
+		}
+		for i, sym := range syms {
+			cs[i].value = uint64(sym)
+		}
+	default:
+		return fmt.Errorf("unrecognized symbol type %q (expected Eq or LGE)", cfg.typ)
+	}
+	return nil
+}
+
+// generate writes Go source code for a list of constants.
+func generate(w io.Writer, cfg config, cs []constant) error {
+	// Sort LGE constants by value to make the generated code easier to
+	// read.
+	if cfg.typ == "LGE" {
+		sort.Slice(cs, func(i, j int) bool { return cs[i].value < cs[j].value })
+	}
+
+	// Generate the code.
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"%s\"; DO NOT EDIT.\n\n", strings.TrimSpace("interngen "+cfg.args))
+	fmt.Fprintf(&buf, "package %s\n\n", cfg.pkg)
+	fmt.Fprintln(&buf, `import "github.com/spakin/intern"`)
+	fmt.Fprintf(&buf, "\n// These constants represent well-known strings interned to intern.%ss.\n", cfg.typ)
+	fmt.Fprintln(&buf, "const (")
+	for _, c := range cs {
+		fmt.Fprintf(&buf, "\t%s intern.%s = %d // %q\n", c.name, cfg.typ, c.value, c.str)
+	}
+	fmt.Fprintln(&buf, ")")
+	fmt.Fprintf(&buf, "\n// init reserves the above constants in the %s symbol table.\n", cfg.typ)
+	fmt.Fprintln(&buf, "func init() {")
+	fmt.Fprintf(&buf, "\terr := intern.Reserve%ss(map[string]intern.%s{\n", cfg.typ, cfg.typ)
+	for _, c := range cs {
+		fmt.Fprintf(&buf, "\t\t%q: %s,\n", c.str, c.name)
+	}
+	fmt.Fprintln(&buf, "\t})")
+	fmt.Fprintln(&buf, "\tif err != nil {")
+	fmt.Fprintln(&buf, "\t\tpanic(err)")
+	fmt.Fprintln(&buf, "\t}")
+	fmt.Fprintln(&buf, "}")
+
+	// Format and output the code.
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// fatal reports an error and exits the program.
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "interngen: %s\n", err)
+	os.Exit(1)
+}
+
+func main() {
+	// Parse the command line.
+	var cfg config
+	flag.StringVar(&cfg.typ, "type", "Eq", "symbol type to generate (Eq or LGE)")
+	flag.StringVar(&cfg.prefix, "prefix", "", "prefix for each constant name")
+	flag.StringVar(&cfg.pkg, "package", os.Getenv("GOPACKAGE"), "name of the generated package")
+	flag.Uint64Var(&cfg.base, "base", 1, "value of the first Eq constant")
+	outName := flag.String("o", "", "output file (default: standard output)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-type Eq|LGE] [-prefix prefix] [-package name] [-base n] [-o file] [file]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	cfg.args = strings.Join(os.Args[1:], " ")
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if cfg.pkg == "" {
+		fatal(fmt.Errorf("no package name was specified"))
+	}
+	if cfg.base == 0 {
+		fatal(fmt.Errorf("the base value must be positive"))
+	}
+
+	// Read the list of strings.
+	var in io.Reader = os.Stdin
+	if flag.NArg() == 1 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	cs, err := readConstants(in, cfg.prefix)
+	if err != nil {
+		fatal(err)
+	}
+
+	// Generate code.
+	err = assignValues(cfg, cs)
+	if err != nil {
+		fatal(err)
+	}
+	var out io.Writer = os.Stdout
+	if *outName != "" {
+		f, err := os.Create(*outName)
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	err = generate(out, cfg, cs)
+	if err != nil {
+		fatal(err)
+	}
+}
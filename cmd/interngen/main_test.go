@@ -0,0 +1,83 @@
+// This file provides unit tests for interngen.
+
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+// TestConstName ensures that strings are converted properly to constant
+// names.
+func TestConstName(t *testing.T) {
+	for s, n := range map[string]string{
+		"select":       "KwSelect",
+		"group by":     "KwGroupBy",
+		"order-by":     "KwOrderBy",
+		"x2":           "KwX2",
+		"ALREADY_UP":   "KwALREADYUP",
+		"  spaced  ":   "KwSpaced",
+		"café au lait": "KwCaféAuLait",
+	} {
+		if c := constName("Kw", s); c != n {
+			t.Fatalf("Expected %q to map to %q but saw %q", s, n, c)
+		}
+	}
+}
+
+// TestReadConstants ensures that input is parsed correctly and that
+// duplicates are rejected.
+func TestReadConstants(t *testing.T) {
+	cs, err := readConstants(strings.NewReader("# Comment\nselect\n\nStar\t*\n"), "Kw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs) != 2 || cs[0].name != "KwSelect" || cs[1].name != "KwStar" || cs[1].str != "*" {
+		t.Fatalf("Unexpected constants %v", cs)
+	}
+	for _, in := range []string{"select\nselect\n", "a b\na-b\n", "*\n"} {
+		_, err = readConstants(strings.NewReader(in), "")
+		if err == nil {
+			t.Fatalf("Failed to reject %q", in)
+		}
+	}
+}
+
+// TestGenerate ensures that generated code parses and declares the expected
+// constants.
+func TestGenerate(t *testing.T) {
+	for _, typ := range []string{"Eq", "LGE"} {
+		t.Run(typ, func(t *testing.T) {
+			cfg := config{typ: typ, prefix: "Kw", pkg: "kw", base: 100}
+			cs, err := readConstants(strings.NewReader("select\nfrom\nwhere\n"), cfg.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = assignValues(cfg, cs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			err = generate(&buf, cfg, cs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = parser.ParseFile(token.NewFileSet(), "kw.go", buf.Bytes(), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			src := strings.Join(strings.Fields(buf.String()), " ")
+			for _, n := range []string{"KwSelect intern." + typ, "KwFrom intern." + typ, "intern.Reserve" + typ + "s"} {
+				if !strings.Contains(src, n) {
+					t.Fatalf("Generated code lacks %q:\n%s", n, src)
+				}
+			}
+			if typ == "Eq" && !strings.Contains(src, "KwSelect intern.Eq = 100") {
+				t.Fatalf("Generated code does not start at the base value:\n%s", src)
+			}
+		})
+	}
+}
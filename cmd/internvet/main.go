@@ -0,0 +1,28 @@
+/*
+Internvet checks Go programs for misuse of the intern package.  It is meant to
+be run by go vet:
+
+	go vet -vettool=$(which internvet) ./...
+
+Internvet runs the following analyzers:
+
+	symcheck    reports ordered comparisons and sorting of intern.Eq and
+	            intern.Eq32 values, arithmetic on symbols, conversions from
+	            integers to symbols, and truncating conversions to intern.Eq32
+	remapcheck  reports discarded results of intern.RemapAllLGEs and stored
+	            LGEs that are never updated after remapping
+*/
+package main
+
+import (
+	"github.com/spakin/intern/analysis/remapcheck"
+	"github.com/spakin/intern/analysis/symcheck"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+func main() {
+	unitchecker.Main(
+		symcheck.Analyzer,
+		remapcheck.Analyzer,
+	)
+}
@@ -0,0 +1,265 @@
+// This file provides a double-array trie for use as an Eq index.
+
+package intern
+
+// datrieEnd is the code of the transition that marks the end of a string.
+// Each byte c is encoded as c+1.
+const datrieEnd = 0
+
+// datrieCodes is the number of distinct transition codes.
+const datrieCodes = 257
+
+// datrieSearch is the number of nodes to examine when searching for unused
+// nodes in which to place a node's children.
+const datrieSearch = 1024
+
+// A DATrie is a double-array trie mapping strings to Eqs.  It implements
+// EqIndex and can therefore replace the Go map the package normally uses to
+// look up Eqs (see SetEqIndex), but it can also be used on its own.  Each
+// node of the trie is an index into two parallel arrays.  The child of node
+// s along transition code c is node t = base[s] + c, provided that
+// check[t] == s.  This makes each step of a lookup a pair of array accesses,
+// and because the trie is ordered by bytes, it supports longest-prefix
+// matching and enumeration of all strings that begin with a given prefix.
+//
+// A DATrie is not safe for concurrent modification, although concurrent
+// calls to its read-only methods are safe.  The zero value is an empty
+// DATrie ready to use.
+type DATrie struct {
+	base  []int32 // Offset of each node's children, or 0 if none
+	check []int32 // Parent of each node, or -1 if unused
+	value []Eq    // Eq stored at each end-of-string node
+	free  int     // Starting point for finding unused nodes
+	n     int     // Number of strings stored
+}
+
+// init allocates the root of an empty DATrie.
+func (t *DATrie) init() {
+	if len(t.check) > 0 {
+		return
+	}
+	t.base = []int32{0}
+	t.check = []int32{-1}
+	t.value = []Eq{0}
+	t.free = 1
+}
+
+// grow extends a DATrie's arrays to include a given node.
+func (t *DATrie) grow(i int) {
+	for len(t.check) <= i {
+		t.base = append(t.base, 0)
+		t.check = append(t.check, -1)
+		t.value = append(t.value, 0)
+	}
+}
+
+// child returns the child of a node along a given code.
+func (t *DATrie) child(s int, c int) (int, bool) {
+	if len(t.check) == 0 || t.base[s] == 0 {
+		return 0, false
+	}
+	i := int(t.base[s]) + c
+	if i >= len(t.check) || t.check[i] != int32(s) {
+		return 0, false
+	}
+	return i, true
+}
+
+// children returns the codes of all of a node's children in increasing
+// order.
+func (t *DATrie) children(s int) []int {
+	var cs []int
+	if t.base[s] == 0 {
+		return cs
+	}
+	b := int(t.base[s])
+	for c := 0; c < datrieCodes && b+c < len(t.check); c++ {
+		if t.check[b+c] == int32(s) {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// findBase returns a base for which every given code leads to an unused
+// node.  To bound the cost of insertion, it examines only a limited number of
+// nodes before resorting to the end of the arrays.
+func (t *DATrie) findBase(cs []int) int {
+	for t.free < len(t.check) && t.check[t.free] != -1 {
+		t.free++
+	}
+	end := t.free + datrieSearch
+	if end > len(t.check) {
+		end = len(t.check)
+	}
+Search:
+	for i := t.free; i < end; i++ {
+		if t.check[i] != -1 {
+			continue
+		}
+		b := i - cs[0]
+		if b < 1 {
+			continue
+		}
+		for _, c := range cs[1:] {
+			if b+c < len(t.check) && t.check[b+c] != -1 {
+				continue Search
+			}
+		}
+		return b
+	}
+	if b := len(t.check) - cs[0]; b >= 1 {
+		return b
+	}
+	return 1
+}
+
+// relocate moves all of a node's children to a new base that can also
+// accommodate an additional code.
+func (t *DATrie) relocate(s int, extra int) {
+	cs := t.children(s)
+	all := append(append([]int(nil), cs...), extra)
+	for i := len(all) - 1; i > 0 && all[i] < all[i-1]; i-- {
+		all[i], all[i-1] = all[i-1], all[i]
+	}
+	b := t.findBase(all)
+	t.grow(b + all[len(all)-1])
+	oldBase := int(t.base[s])
+	for _, c := range cs {
+		o, n := oldBase+c, b+c
+		t.base[n] = t.base[o]
+		t.check[n] = int32(s)
+		t.value[n] = t.value[o]
+		for _, gc := range t.children(o) {
+			t.check[int(t.base[o])+gc] = int32(n)
+		}
+		t.base[o] = 0
+		t.check[o] = -1
+		t.value[o] = 0
+	}
+	t.base[s] = int32(b)
+}
+
+// addChild adds a child to a node along a given code and returns the child.
+func (t *DATrie) addChild(s int, c int) int {
+	if t.base[s] == 0 {
+		b := t.findBase([]int{c})
+		t.grow(b + c)
+		t.base[s] = int32(b)
+	} else if i := int(t.base[s]) + c; i < len(t.check) && t.check[i] != -1 {
+		t.relocate(s, c)
+	}
+	i := int(t.base[s]) + c
+	t.grow(i)
+	t.check[i] = int32(s)
+	return i
+}
+
+// Insert associates a string with an Eq, replacing any existing association.
+func (t *DATrie) Insert(str string, e Eq) {
+	t.init()
+	s := 0
+	for i := 0; i <= len(str); i++ {
+		c := datrieEnd
+		if i < len(str) {
+			c = int(str[i]) + 1
+		}
+		next, ok := t.child(s, c)
+		if !ok {
+			next = t.addChild(s, c)
+			if c == datrieEnd {
+				t.n++
+			}
+		}
+		s = next
+	}
+	t.value[s] = e
+}
+
+// Lookup returns the Eq associated with a string.  The second return value
+// indicates whether the string was found.
+func (t *DATrie) Lookup(str string) (Eq, bool) {
+	s := 0
+	for i := 0; i < len(str); i++ {
+		var ok bool
+		s, ok = t.child(s, int(str[i])+1)
+		if !ok {
+			return 0, false
+		}
+	}
+	s, ok := t.child(s, datrieEnd)
+	if !ok {
+		return 0, false
+	}
+	return t.value[s], true
+}
+
+// LongestPrefix returns the Eq associated with the longest string in the trie
+// that is a prefix of a given string, along with that prefix's length in
+// bytes.  The third return value is false if no string in the trie is a
+// prefix of the given string.
+func (t *DATrie) LongestPrefix(str string) (Eq, int, bool) {
+	var e Eq
+	n, found := 0, false
+	s := 0
+	for i := 0; ; i++ {
+		if end, ok := t.child(s, datrieEnd); ok {
+			e, n, found = t.value[end], i, true
+		}
+		if i == len(str) {
+			break
+		}
+		var ok bool
+		s, ok = t.child(s, int(str[i])+1)
+		if !ok {
+			break
+		}
+	}
+	return e, n, found
+}
+
+// EachPrefix calls a function on each string in the trie that begins with a
+// given prefix, along with its Eq, in sorted order, until the function
+// returns false.
+func (t *DATrie) EachPrefix(prefix string, f func(s string, e Eq) bool) {
+	s := 0
+	for i := 0; i < len(prefix); i++ {
+		var ok bool
+		s, ok = t.child(s, int(prefix[i])+1)
+		if !ok {
+			return
+		}
+	}
+	t.walk(s, []byte(prefix), f)
+}
+
+// walk calls a function on each string below a given node in sorted order.
+// It returns false if the function asked to stop.
+func (t *DATrie) walk(s int, buf []byte, f func(s string, e Eq) bool) bool {
+	if len(t.check) == 0 {
+		return true
+	}
+	for _, c := range t.children(s) {
+		i := int(t.base[s]) + c
+		if c == datrieEnd {
+			if !f(string(buf), t.value[i]) {
+				return false
+			}
+			continue
+		}
+		if !t.walk(i, append(buf, byte(c-1)), f) {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the number of strings in the trie.
+func (t *DATrie) Len() int {
+	return t.n
+}
+
+// Reset removes all strings from the trie.
+func (t *DATrie) Reset() {
+	*t = DATrie{}
+}
@@ -0,0 +1,156 @@
+// This file provides unit tests for the double-array trie and for replacing
+// the Eq table's index.
+
+package intern_test
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// TestDATrie compares a DATrie to a Go map.
+func TestDATrie(t *testing.T) {
+	// Insert a mix of random and similar strings, including prefixes of
+	// other strings and the empty string.
+	var dt intern.DATrie
+	strs := append(generateRandomStrings(5000), generateSimilarStrings(500)...)
+	strs = append(strs, ozChars...)
+	strs = append(strs, "", "Dorothy", "Dorothy Gale's dog", "\x00", "\xff\xff")
+	m := make(map[string]intern.Eq, len(strs))
+	for i, s := range strs {
+		dt.Insert(s, intern.Eq(i+1))
+		m[s] = intern.Eq(i + 1)
+	}
+	dt.Insert("Dorothy", 12345) // Replace an existing Eq.
+	m["Dorothy"] = 12345
+	if dt.Len() != len(m) {
+		t.Fatalf("Expected %d strings but saw %d", len(m), dt.Len())
+	}
+	for s, e := range m {
+		if e2, ok := dt.Lookup(s); !ok || e2 != e {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, e, e2)
+		}
+	}
+	for _, s := range []string{"Doroth", "Dorothy Gale's", "Dorothy Gale's dog!", "\xff", "Alice"} {
+		if _, ok := dt.Lookup(s); ok {
+			t.Fatalf("Unexpectedly found %q", s)
+		}
+	}
+
+	// Test longest-prefix matching.
+	for _, tc := range []struct {
+		str    string
+		prefix string
+	}{
+		{"Dorothy Gale's dog Toto", "Dorothy Gale's dog"},
+		{"Dorothy Gale's cat", "Dorothy Gale"},
+		{"Dorothy Gal", "Dorothy"},
+		{"Alice", ""},
+	} {
+		e, n, ok := dt.LongestPrefix(tc.str)
+		if !ok || n != len(tc.prefix) || e != m[tc.prefix] {
+			t.Fatalf("Expected the longest prefix of %q to be %q but saw length %d", tc.str, tc.prefix, n)
+		}
+	}
+
+	// Test prefix enumeration.
+	sorted := make([]string, 0, len(m))
+	for s := range m {
+		sorted = append(sorted, s)
+	}
+	sort.Strings(sorted)
+	for _, p := range []string{"", "D", "Dorothy", "String comparisons", "Zz", "\xff"} {
+		var want, got []string
+		for _, s := range sorted {
+			if strings.HasPrefix(s, p) {
+				want = append(want, s)
+			}
+		}
+		dt.EachPrefix(p, func(s string, e intern.Eq) bool {
+			if e != m[s] {
+				t.Fatalf("Expected %q to map to %d but saw %d", s, m[s], e)
+			}
+			got = append(got, s)
+			return true
+		})
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("Expected prefix %q to visit %d strings but saw %d", p, len(want), len(got))
+		}
+	}
+
+	// Ensure that resetting the trie empties it.
+	dt.Reset()
+	if _, ok := dt.Lookup("Dorothy"); ok || dt.Len() != 0 {
+		t.Fatal("Failed to reset the trie")
+	}
+	if _, _, ok := dt.LongestPrefix("Dorothy"); ok {
+		t.Fatal("Found a prefix in an empty trie")
+	}
+}
+
+// TestSetEqIndex ensures that the Eq table behaves the same with a DATrie
+// index as with the default index.
+func TestSetEqIndex(t *testing.T) {
+	// Install a DATrie after creating some Eqs.
+	intern.ForgetAllEqs()
+	defer intern.SetEqIndex(nil)
+	defer intern.ForgetAllEqs()
+	syms := intern.NewEqMulti(ozChars[:50])
+	dt := &intern.DATrie{}
+	if err := intern.SetEqIndex(dt); err != nil {
+		t.Fatal(err)
+	}
+	syms = append(syms, intern.NewEqMulti(ozChars[50:])...)
+	if dt.Len() != len(ozChars) {
+		t.Fatalf("Expected the index to contain %d strings but saw %d", len(ozChars), dt.Len())
+	}
+	for i, s := range ozChars {
+		if e := intern.NewEq(s); e != syms[i] {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, syms[i], e)
+		}
+		if e, ok := intern.LookupEq(s); !ok || e != syms[i] {
+			t.Fatalf("Failed to look up %q", s)
+		}
+	}
+	if err := intern.ReserveEqs(map[string]intern.Eq{ozChars[0]: syms[0], "Alice": 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := dt.Lookup("Alice"); !ok || e != 1000 {
+		t.Fatal("ReserveEqs failed to update the index")
+	}
+
+	// Ensure that a snapshot and a frozen table work as usual.
+	var buf bytes.Buffer
+	if err := intern.WriteEqSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	intern.FreezeEqs()
+	expectFrozen(t, intern.SetEqIndex(nil))
+	if e, ok := intern.LookupEq("Alice"); !ok || e != 1000 {
+		t.Fatal("Failed to look up Alice in a frozen table")
+	}
+	if err := intern.ReadEqSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := intern.LookupEq("Alice"); !ok || e != 1000 {
+		t.Fatal("Failed to look up Alice in a snapshot")
+	}
+
+	// Ensure that ForgetAllEqs empties but retains the index and that
+	// restoring the default index preserves existing Eqs.
+	intern.ForgetAllEqs()
+	e := intern.NewEq("Alice")
+	if e2, ok := dt.Lookup("Alice"); !ok || e2 != e || dt.Len() != 1 {
+		t.Fatal("ForgetAllEqs failed to reset the index")
+	}
+	if err := intern.SetEqIndex(nil); err != nil {
+		t.Fatal(err)
+	}
+	if e2, ok := intern.LookupEq("Alice"); !ok || e2 != e {
+		t.Fatal("Failed to restore the default index")
+	}
+}
@@ -0,0 +1,236 @@
+// This file provides support for replicating an Eq table incrementally from
+// a leader to any number of followers.
+
+package intern
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"sort"
+)
+
+// deltaMagic begins every delta.  The final byte is a version number.
+const deltaMagic = "idelta\x00\x01"
+
+// An Entry pairs an interned string with its Eq.
+type Entry struct {
+	Eq  Eq
+	Str string
+}
+
+// diffEqs returns all of a state's entries with Eqs greater than a given Eq,
+// sorted by Eq.
+func (st *state) diffEqs(since Eq) []Entry {
+	st.RLock()
+	defer st.RUnlock()
+	var ents []Entry
+	st.eachSym(func(s string, sym symbol) {
+		if Eq(sym) > since {
+			ents = append(ents, Entry{Eq: Eq(sym), Str: s})
+		}
+	})
+	sort.Slice(ents, func(i, j int) bool { return ents[i].Eq < ents[j].Eq })
+	return ents
+}
+
+// DiffEqs returns every Eq greater than a given Eq, along with its string,
+// sorted by Eq.  Because NewEq assigns Eqs sequentially in EqTableMode (see
+// SetEqMode), a leader that remembers the largest Eq it has sent to a
+// follower can pass that Eq to DiffEqs to obtain every Eq the follower
+// lacks.  Pass 0 to obtain every Eq.  In EqHashMode, Eqs are not assigned in
+// order, so only DiffEqs(0) is meaningful.  Strings packed into inline Eqs
+// are never returned.
+//
+// Eqs pinned by ReserveEqs or ReserveEqRange lie outside NewEq's sequence,
+// so NewEq may later assign Eqs smaller than a reserved Eq that has already
+// been sent.  A leader that reserves Eqs should therefore exclude them when
+// determining the largest Eq it has sent.  The reserved Eqs are then sent
+// again, which ApplyEqDelta accepts.
+func DiffEqs(since Eq) []Entry {
+	return eq.diffEqs(since)
+}
+
+// WriteEqDelta writes a list of entries, such as those returned by DiffEqs,
+// to an io.Writer for ApplyEqDelta to read.  The format is a magic string;
+// the number of entries; and, for each entry in order of increasing Eq, the
+// difference between its Eq and the previous entry's Eq (or 0), the length
+// of its string, and the string's contents.  All integers are written as
+// unsigned varints, so each sequentially assigned Eq occupies a single byte.
+// WriteEqDelta returns an error, and writes nothing, if two entries share an
+// Eq.
+func WriteEqDelta(w io.Writer, ents []Entry) error {
+	if !sort.SliceIsSorted(ents, func(i, j int) bool { return ents[i].Eq < ents[j].Eq }) {
+		ents = append([]Entry(nil), ents...)
+		sort.Slice(ents, func(i, j int) bool { return ents[i].Eq < ents[j].Eq })
+	}
+	for i := 1; i < len(ents); i++ {
+		if e0, e := ents[i-1], ents[i]; e.Eq == e0.Eq {
+			return conflict(e.Str, "Unable to write %q as Eq %d; the delta also assigns it to %q", e.Str, e.Eq, e0.Str)
+		}
+	}
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 0, 2*binary.MaxVarintLen64)
+	buf = append(buf, deltaMagic...)
+	buf = binary.AppendUvarint(buf, uint64(len(ents)))
+	if _, err := bw.Write(buf); err != nil {
+		return err
+	}
+	var prev Eq
+	for _, e := range ents {
+		buf = binary.AppendUvarint(buf[:0], uint64(e.Eq-prev))
+		buf = binary.AppendUvarint(buf, uint64(len(e.Str)))
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+		if _, err := bw.WriteString(e.Str); err != nil {
+			return err
+		}
+		prev = e.Eq
+	}
+	return bw.Flush()
+}
+
+// deltaReader is the interface ApplyEqDelta needs to read a single delta
+// without consuming any of the data that follows it.
+type deltaReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// readDelta reads a delta written by WriteEqDelta.
+func readDelta(r deltaReader) ([]Entry, error) {
+	hdr := make([]byte, len(deltaMagic))
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, badSnapshot("Delta is truncated")
+	}
+	if string(hdr) != deltaMagic {
+		return nil, badSnapshot("Unrecognized delta format")
+	}
+	n, err := readDeltaUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	var ents []Entry
+	var prev Eq
+	for i := uint64(0); i < n; i++ {
+		d, err := readDeltaUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		ln, err := readDeltaUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && d == 0 {
+			return nil, badSnapshot("Delta repeats Eq %d", prev)
+		}
+		if prev+Eq(d) < prev || prev+Eq(d) == 0 {
+			return nil, badSnapshot("Delta contains an invalid Eq")
+		}
+		if ln > maxSnapshotString {
+			return nil, badSnapshot("Delta string is too long")
+		}
+		str := make([]byte, ln)
+		if _, err := io.ReadFull(r, str); err != nil {
+			return nil, badSnapshot("Delta is truncated")
+		}
+		prev += Eq(d)
+		ents = append(ents, Entry{Eq: prev, Str: string(str)})
+	}
+	return ents, nil
+}
+
+// readDeltaUvarint reads an unsigned varint and reports a truncated delta as
+// a PkgError.
+func readDeltaUvarint(r io.ByteReader) (uint64, error) {
+	v, err := binary.ReadUvarint(r)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, badSnapshot("Delta is truncated")
+	}
+	return v, err
+}
+
+// applyEntries assigns each of a list of entries' strings its Eq.  It applies
+// nothing if any entry conflicts with an existing Eq or with another entry.
+func (st *state) applyEntries(ents []Entry) error {
+	st.Lock()
+	defer st.Unlock()
+	if err := st.checkFrozen("", "Eq"); err != nil {
+		return err
+	}
+	seen := make(map[string]Eq, len(ents))
+	strs := make(map[Eq]string, len(ents))
+	for _, e := range ents {
+		if _, ok := st.inlineEq(e.Str); ok || (st.inline.Load() && symbol(e.Eq)&inlineTag != 0) {
+			return conflict(e.Str, "Unable to apply %q as Eq %d; it conflicts with inline Eqs", e.Str, e.Eq)
+		}
+		if e2, ok := seen[e.Str]; ok && e2 != e.Eq {
+			return conflict(e.Str, "Unable to apply %q as both Eq %d and Eq %d", e.Str, e2, e.Eq)
+		}
+		if s, ok := strs[e.Eq]; ok && s != e.Str {
+			return conflict(e.Str, "Unable to apply both %q and %q as Eq %d", s, e.Str, e.Eq)
+		}
+		seen[e.Str] = e.Eq
+		strs[e.Eq] = e.Str
+		if old, ok := st.lookupUnfrozen(e.Str); ok && Eq(old) != e.Eq {
+			return conflict(e.Str, "Unable to apply %q as Eq %d; it is already Eq %d", e.Str, e.Eq, old)
+		}
+		if s, ok := st.symToStr[symbol(e.Eq)]; ok && s != e.Str {
+			return conflict(e.Str, "Unable to apply %q as Eq %d; it is already %q", e.Str, e.Eq, s)
+		}
+	}
+	for _, e := range ents {
+		// Entries already applied are skipped so that they are not
+		// indexed twice.
+		if _, ok := st.lookupUnfrozen(e.Str); ok {
+			continue
+		}
+		st.setSym(e.Str, symbol(e.Eq))
+	}
+	return nil
+}
+
+// applyEqDelta reads a single delta from an io.Reader and applies it to a
+// state.
+func (st *state) applyEqDelta(r io.Reader) error {
+	dr, ok := r.(deltaReader)
+	if !ok {
+		dr = bufio.NewReader(r)
+	}
+	ents, err := readDelta(dr)
+	if err != nil {
+		return err
+	}
+	return st.applyEntries(ents)
+}
+
+// ApplyEqDelta reads one delta, as written by WriteEqDelta, from an io.Reader
+// and assigns each of its strings the same Eq it has in the leader's table.
+// A follower that applies every delta a leader writes therefore agrees with
+// the leader on every Eq.  ApplyEqDelta returns an error, and applies
+// nothing, if the delta is malformed or if any of its strings or Eqs was
+// already assigned differently, as could happen if the follower called NewEq
+// on a string the leader had not yet sent.  It returns io.EOF if the reader
+// is exhausted before the delta begins.  To read successive deltas from a
+// stream such as a network connection, wrap the stream once in a bufio.Reader
+// and pass that to each call; ApplyEqDelta never reads past the end of a
+// delta from a reader that implements io.ByteReader.
+func ApplyEqDelta(r io.Reader) error {
+	return eq.applyEqDelta(r)
+}
+
+// DiffEqs performs the same operation as the package-level DiffEqs but on an
+// EqTable.
+func (t *EqTable) DiffEqs(since Eq) []Entry {
+	return t.st.diffEqs(since)
+}
+
+// ApplyEqDelta performs the same operation as the package-level ApplyEqDelta
+// but on an EqTable.
+func (t *EqTable) ApplyEqDelta(r io.Reader) error {
+	return t.st.applyEqDelta(r)
+}
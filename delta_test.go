@@ -0,0 +1,223 @@
+// This file provides unit tests for incremental replication of Eq tables.
+
+package intern_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// TestEqDeltaPipe replicates a growing Eq table to a follower over a
+// connection and ensures that both sides agree on every Eq.
+func TestEqDeltaPipe(t *testing.T) {
+	leader, follower := intern.NewEqTable(), intern.NewEqTable()
+	lConn, fConn := net.Pipe()
+
+	// The leader interns ozChars in batches, sending each batch's Eqs as a
+	// separate delta.
+	errs := make(chan error, 1)
+	go func() {
+		defer lConn.Close()
+		var sent intern.Eq
+		for i := 0; i < len(ozChars); i += 10 {
+			j := i + 10
+			if j > len(ozChars) {
+				j = len(ozChars)
+			}
+			leader.NewEqMulti(ozChars[i:j])
+			ents := leader.DiffEqs(sent)
+			if err := intern.WriteEqDelta(lConn, ents); err != nil {
+				errs <- err
+				return
+			}
+			if len(ents) > 0 {
+				sent = ents[len(ents)-1].Eq
+			}
+		}
+		errs <- nil
+	}()
+
+	// The follower applies deltas until the leader hangs up.
+	br := bufio.NewReader(fConn)
+	for {
+		err := follower.ApplyEqDelta(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if leader.Len() != follower.Len() {
+		t.Fatalf("Expected %d strings but saw %d", leader.Len(), follower.Len())
+	}
+	for _, s := range ozChars {
+		e1, _ := leader.LookupEq(s)
+		e2, ok := follower.LookupEq(s)
+		if !ok || e1 != e2 {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, e1, e2)
+		}
+	}
+}
+
+// TestEqDeltaGlobal replicates the global Eq table to an EqTable and back.
+func TestEqDeltaGlobal(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	eqs := intern.NewEqMulti(ozChars)
+	var buf bytes.Buffer
+	if err := intern.WriteEqDelta(&buf, intern.DiffEqs(0)); err != nil {
+		t.Fatal(err)
+	}
+	follower := intern.NewEqTable()
+	if err := follower.ApplyEqDelta(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range eqs {
+		if s := follower.String(e); s != ozChars[i] {
+			t.Fatalf("Expected %q but saw %q", ozChars[i], s)
+		}
+	}
+
+	// Applying the same delta again is harmless.
+	buf.Reset()
+	if err := intern.WriteEqDelta(&buf, follower.DiffEqs(eqs[49])); err != nil {
+		t.Fatal(err)
+	}
+	if err := intern.ApplyEqDelta(&buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestEqDeltaOverlap ensures that applying a delta that overlaps one already
+// applied does not index any string twice.
+func TestEqDeltaOverlap(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.EnableEqSearchIndex(false)
+	defer intern.ForgetAllEqs()
+	intern.EnableEqSearchIndex(true)
+	leader := intern.NewEqTable()
+	leader.NewEqMulti([]string{"alphabet", "beta"})
+	for _, s := range []string{"gamma", "alphanumeric"} {
+		var buf bytes.Buffer
+		leader.NewEq(s)
+		if err := intern.WriteEqDelta(&buf, leader.DiffEqs(0)); err != nil {
+			t.Fatal(err)
+		}
+		if err := intern.ApplyEqDelta(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectEqs(t, "ContainsEqs", intern.ContainsEqs("alph"), []string{"alphabet", "alphanumeric"})
+	expectEqs(t, "ContainsEqs", intern.ContainsEqs("et"), []string{"alphabet", "beta"})
+}
+
+// TestEqDeltaReserved ensures that a leader that reserves an Eq must exclude
+// it when remembering the largest Eq it has sent.
+func TestEqDeltaReserved(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	if err := intern.ReserveEqs(map[string]intern.Eq{"x": 100}); err != nil {
+		t.Fatal(err)
+	}
+	alpha := intern.NewEq("alpha")
+	ents := intern.DiffEqs(0)
+	if len(ents) != 2 || ents[1].Eq != 100 {
+		t.Fatalf("Expected alpha and the reserved Eq but saw %v", ents)
+	}
+	beta := intern.NewEq("beta")
+	if beta > 100 {
+		t.Fatalf("Expected beta to precede the reserved Eq but saw %d", beta)
+	}
+
+	// The largest Eq sent skips beta, but the largest unreserved Eq sent
+	// does not.
+	if ents = intern.DiffEqs(100); len(ents) != 0 {
+		t.Fatalf("Expected no entries but saw %v", ents)
+	}
+	ents = intern.DiffEqs(alpha)
+	if len(ents) != 2 || ents[0].Str != "beta" || ents[1].Str != "x" {
+		t.Fatalf("Expected beta and x but saw %v", ents)
+	}
+	follower := intern.NewEqTable()
+	for _, since := range []intern.Eq{0, alpha} {
+		var buf bytes.Buffer
+		if err := intern.WriteEqDelta(&buf, intern.DiffEqs(since)); err != nil {
+			t.Fatal(err)
+		}
+		if err := follower.ApplyEqDelta(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if follower.Len() != 3 || follower.String(beta) != "beta" {
+		t.Fatalf("Expected the follower to learn beta but saw %d strings", follower.Len())
+	}
+}
+
+// TestEqDeltaErrors ensures that ApplyEqDelta rejects conflicting and
+// malformed deltas without applying any of their entries.
+func TestEqDeltaErrors(t *testing.T) {
+	follower := intern.NewEqTable()
+	e := follower.NewEq("Dorothy")
+	var buf bytes.Buffer
+	ents := []intern.Entry{{Eq: e + 1, Str: "Toto"}, {Eq: e, Str: "Ozma"}}
+	if err := intern.WriteEqDelta(&buf, ents); err != nil {
+		t.Fatal(err)
+	}
+	var pe *intern.PkgError
+	err := follower.ApplyEqDelta(&buf)
+	if !errors.As(err, &pe) || pe.Code != intern.ErrConflict || pe.Str != "Ozma" {
+		t.Fatalf("Expected a conflict error but saw %v", err)
+	}
+	if _, ok := follower.LookupEq("Toto"); ok {
+		t.Fatal("A rejected delta was partially applied")
+	}
+
+	// Truncate a valid delta.
+	buf.Reset()
+	if err = intern.WriteEqDelta(&buf, ents[:1]); err != nil {
+		t.Fatal(err)
+	}
+	err = follower.ApplyEqDelta(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if !errors.As(err, &pe) || pe.Code != intern.ErrBadSnapshot {
+		t.Fatalf("Expected a malformed-delta error but saw %v", err)
+	}
+	err = follower.ApplyEqDelta(bytes.NewReader([]byte("not a delta")))
+	if !errors.As(err, &pe) || pe.Code != intern.ErrBadSnapshot {
+		t.Fatalf("Expected a malformed-delta error but saw %v", err)
+	}
+
+	// Assign two strings the same Eq.
+	dup := []intern.Entry{{Eq: 5, Str: "alpha"}, {Eq: 5, Str: "beta"}}
+	buf.Reset()
+	err = intern.WriteEqDelta(&buf, dup)
+	if !errors.As(err, &pe) || pe.Code != intern.ErrConflict {
+		t.Fatalf("Expected a conflict error but saw %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatal("A rejected delta was partially written")
+	}
+	raw := []byte("idelta\x00\x01")
+	raw = append(raw, 2, 5, 5)
+	raw = append(raw, "alpha"...)
+	raw = append(raw, 0, 4)
+	raw = append(raw, "beta"...)
+	err = follower.ApplyEqDelta(bytes.NewReader(raw))
+	if !errors.As(err, &pe) || pe.Code != intern.ErrBadSnapshot {
+		t.Fatalf("Expected a malformed-delta error but saw %v", err)
+	}
+	for _, s := range []string{"alpha", "beta"} {
+		if _, ok := follower.LookupEq(s); ok {
+			t.Fatalf("A rejected delta assigned %q an Eq", s)
+		}
+	}
+}
@@ -5,7 +5,9 @@ package intern
 
 // An Eq is a string that has been interned to an integer.  Eq supports only
 // equality and inequality comparisons, not greater than/less than comparisons.
-// (No checks are performed to enforce that usage model, unfortunately.)
+// (No checks are performed at run time to enforce that usage model,
+// unfortunately, but the symcheck analyzer in the analysis/symcheck directory
+// reports violations at compile time.)
 type Eq symbol
 
 // eq maintains all the state needed to manipulate Eqs.
@@ -18,51 +20,147 @@ func init() {
 
 // assignEq assigns the next available Eq symbol to a string and returns the
 // new symbol.  If the string already has an Eq associated with it, return the
-// old Eq without allocating a new one.
-func assignEq(s string) Eq {
+// old Eq without allocating a new one.  assignEq returns an error if the
+// string is new but the table is frozen.
+func (st *state) assignEq(s string) (symbol, error) {
 	// Check if the string was already assigned a symbol.
-	sym, ok := eq.strToSym[s]
+	sym, ok := st.lookup(s)
 	if ok {
-		return Eq(sym)
+		return sym, nil
+	}
+	if err := st.checkFrozen(s, "Eq"); err != nil {
+		return 0, err
+	}
+	if st.hashed {
+		return st.assignHashedEq(s)
 	}
 
-	// We haven't seen this string before.  Find a symbol for it.
-	sym = symbol(len(eq.symToStr) + 1)
-	eq.symToStr[sym] = s
-	eq.strToSym[s] = sym
-	return Eq(sym)
+	// We haven't seen this string before.  Find a symbol for it, skipping
+	// over any symbols that were reserved by ReserveEqs or
+	// ReserveEqRange.
+	sym = st.next
+	for {
+		if r, ok := st.inReservedRange(sym); ok {
+			sym = r.hi + 1
+			continue
+		}
+		if _, taken := st.symToStr[sym]; !taken {
+			break
+		}
+		sym++
+	}
+	st.next = sym + 1
+	st.setSym(s, sym)
+	return sym, nil
+}
+
+// newEq maps a string to an Eq symbol.  It returns an error if the string was
+// not previously interned and the table is frozen.
+func (st *state) newEq(s string) (Eq, error) {
+	if sym, ok := st.inlineEq(s); ok {
+		return Eq(sym), nil
+	}
+	if ft := st.frozenTable(); ft != nil {
+		if sym, ok := ft.lookup(s); ok {
+			return Eq(sym), nil
+		}
+	}
+	st.Lock()
+	defer st.Unlock()
+	sym, err := st.assignEq(s)
+	return Eq(sym), err
 }
 
 // NewEq maps a string to an Eq symbol.  It guarantees that two equal strings
-// will always map to the same Eq.
+// will always map to the same Eq.  NewEq panics if given a new string after
+// FreezeEqs has been called; use DefaultEqTable().Intern to receive an error
+// instead.
 func NewEq(s string) Eq {
-	eq.Lock()
-	defer eq.Unlock()
-	return assignEq(s)
+	sym, err := eq.newEq(s)
+	if err != nil {
+		panic(err)
+	}
+	return sym
 }
 
 // NewEqMulti performs the same operation as NewEq but accepts a slice of
 // strings instead of an individual string.  This amortizes some costs when
-// allocating a large number of Eqs at once.
+// allocating a large number of Eqs at once.  Like NewEq, NewEqMulti panics
+// if given a new string after FreezeEqs has been called.
 func NewEqMulti(ss []string) []Eq {
-	eq.Lock()
-	defer eq.Unlock()
+	syms, err := eq.newEqMulti(ss)
+	if err != nil {
+		panic(err)
+	}
+	return syms
+}
+
+// newEqMulti maps a slice of strings to Eq symbols.  It returns an error if
+// any string was not previously interned and the table is frozen.
+func (st *state) newEqMulti(ss []string) ([]Eq, error) {
 	syms := make([]Eq, len(ss))
+	if ft := st.frozenTable(); ft != nil {
+		for i, s := range ss {
+			if sym, ok := st.inlineEq(s); ok {
+				syms[i] = Eq(sym)
+				continue
+			}
+			sym, ok := ft.lookup(s)
+			if !ok {
+				return nil, st.checkFrozen(s, "Eq")
+			}
+			syms[i] = Eq(sym)
+		}
+		return syms, nil
+	}
+	st.Lock()
+	defer st.Unlock()
 	for i, s := range ss {
-		syms[i] = assignEq(s)
+		if sym, ok := st.inlineEq(s); ok {
+			syms[i] = Eq(sym)
+			continue
+		}
+		sym, err := st.assignEq(s)
+		if err != nil {
+			return nil, err
+		}
+		syms[i] = Eq(sym)
 	}
-	return syms
+	return syms, nil
+}
+
+// LookupEq returns the Eq associated with a string without allocating a new
+// Eq.  The second return value indicates whether the string was found.
+func LookupEq(s string) (Eq, bool) {
+	return eq.lookupEq(s)
+}
+
+// lookupEq returns the Eq associated with a string without allocating a new
+// Eq.
+func (st *state) lookupEq(s string) (Eq, bool) {
+	if sym, ok := st.inlineEq(s); ok {
+		return Eq(sym), true
+	}
+	if ft := st.frozenTable(); ft != nil {
+		sym, ok := ft.lookup(s)
+		return Eq(sym), ok
+	}
+	st.RLock()
+	defer st.RUnlock()
+	sym, ok := st.lookup(s)
+	return Eq(sym), ok
 }
 
 // String converts an Eq back to a string.  It panics if given an Eq that was
 // not created using NewEq.
 func (s Eq) String() string {
-	return eq.toString(symbol(s), "Eq")
+	return eq.eqString(symbol(s))
 }
 
 // ForgetAllEqs discards all existing mappings from strings to Eqs so the
 // associated memory can be reclaimed.  Use this function only when you know
-// for sure that no previously mapped Eqs will subsequently be used.
+// for sure that no previously mapped Eqs will subsequently be used.  If the
+// Eq table was frozen, ForgetAllEqs thaws it.
 func ForgetAllEqs() {
 	eq.Lock()
 	eq.forgetAll()
@@ -72,28 +170,30 @@ func ForgetAllEqs() {
 // MarshalText converts an Eq to a string and that string to a slice of bytes.
 // With this method, Eq implements the encoding.TextMarshaler interface.
 func (s *Eq) MarshalText() ([]byte, error) {
-	return []byte(eq.toString(symbol(*s), "Eq")), nil
+	return []byte(eq.eqString(symbol(*s))), nil
 }
 
 // UnmarshalText converts an slice of bytes to a string then interns that
 // string to an Eq.  With this method, Eq implements the
 // encoding.TextUnmarshaler interface.
 func (s *Eq) UnmarshalText(text []byte) error {
-	*s = NewEq(string(text))
-	return nil
+	var err error
+	*s, err = eq.newEq(string(text))
+	return err
 }
 
 // MarshalBinary converts an Eq to a string and that string to a slice of
 // bytes.  With this method, Eq implements the encoding.BinaryMarshaler
 // interface.
 func (s *Eq) MarshalBinary() ([]byte, error) {
-	return []byte(eq.toString(symbol(*s), "Eq")), nil
+	return []byte(eq.eqString(symbol(*s))), nil
 }
 
 // UnmarshalBinary converts an slice of bytes to a string then interns that
 // string to an Eq.  With this method, Eq implements the
 // encoding.BinaryUnmarshaler interface.
 func (s *Eq) UnmarshalBinary(data []byte) error {
-	*s = NewEq(string(data))
-	return nil
+	var err error
+	*s, err = eq.newEq(string(data))
+	return err
 }
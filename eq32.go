@@ -0,0 +1,100 @@
+// This file provides the Eq32 type, a compact variant of Eq.
+
+package intern
+
+import (
+	"fmt"
+	"math"
+)
+
+// An Eq32 is an Eq that occupies only 32 bits.  Eq32s share the Eq table, so
+// an Eq32 and an Eq that represent the same string have the same value and
+// convert freely to each other.  Eq32s halve the memory needed by large
+// slices and structs of Eqs, at the cost of an error when an Eq exceeds
+// 2^32-1, which can happen if the table holds more than about four billion
+// strings, if ReserveEqs or ReserveEqRange reserved large Eqs, or if the Eq
+// mode is not EqTableMode (see SetEqMode).  In EqHashMode in particular,
+// nearly every Eq exceeds 2^32-1.
+type Eq32 uint32
+
+// Eq32 converts an Eq to an Eq32.  It returns an error if the Eq does not fit
+// in 32 bits.
+func (s Eq) Eq32() (Eq32, error) {
+	if s > math.MaxUint32 {
+		e := &PkgError{
+			Code: ErrOutOfRange,
+			msg:  fmt.Sprintf("Eq %d does not fit in an Eq32", uint64(s)),
+		}
+		return 0, e
+	}
+	return Eq32(s), nil
+}
+
+// Eq converts an Eq32 to an Eq.  This conversion always succeeds.
+func (s Eq32) Eq() Eq {
+	return Eq(s)
+}
+
+// NewEq32 maps a string to an Eq32 symbol.  It returns an error if the
+// string's Eq does not fit in 32 bits or if the string is new and the Eq
+// table is frozen.
+func NewEq32(s string) (Eq32, error) {
+	e, err := eq.newEq(s)
+	if err != nil {
+		return 0, err
+	}
+	e32, err := e.Eq32()
+	if err != nil {
+		err.(*PkgError).Str = s
+	}
+	return e32, err
+}
+
+// LookupEq32 returns the Eq32 associated with a string without allocating a
+// new Eq.  The second return value indicates whether the string was found
+// and its Eq fits in 32 bits.
+func LookupEq32(s string) (Eq32, bool) {
+	e, ok := LookupEq(s)
+	if !ok || e > math.MaxUint32 {
+		return 0, false
+	}
+	return Eq32(e), true
+}
+
+// String converts an Eq32 back to a string.  It panics if given an Eq32 that
+// was not created using NewEq32 or converted from a valid Eq.
+func (s Eq32) String() string {
+	return eq.toString(symbol(s), "Eq32")
+}
+
+// MarshalText converts an Eq32 to a string and that string to a slice of
+// bytes.  With this method, Eq32 implements the encoding.TextMarshaler
+// interface.
+func (s *Eq32) MarshalText() ([]byte, error) {
+	return []byte(eq.toString(symbol(*s), "Eq32")), nil
+}
+
+// UnmarshalText converts an slice of bytes to a string then interns that
+// string to an Eq32.  With this method, Eq32 implements the
+// encoding.TextUnmarshaler interface.
+func (s *Eq32) UnmarshalText(text []byte) error {
+	var err error
+	*s, err = NewEq32(string(text))
+	return err
+}
+
+// MarshalBinary converts an Eq32 to a string and that string to a slice of
+// bytes.  With this method, Eq32 implements the encoding.BinaryMarshaler
+// interface.
+func (s *Eq32) MarshalBinary() ([]byte, error) {
+	return []byte(eq.toString(symbol(*s), "Eq32")), nil
+}
+
+// UnmarshalBinary converts an slice of bytes to a string then interns that
+// string to an Eq32.  With this method, Eq32 implements the
+// encoding.BinaryUnmarshaler interface.
+func (s *Eq32) UnmarshalBinary(data []byte) error {
+	var err error
+	*s, err = NewEq32(string(data))
+	return err
+}
@@ -0,0 +1,109 @@
+// This file provides unit tests for the Eq32 type.
+
+package intern_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"testing"
+	"unsafe"
+
+	"github.com/spakin/intern"
+)
+
+// TestEq32 ensures that Eq32s and Eqs convert to each other.
+func TestEq32(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	for _, s := range ozChars {
+		e32, err := intern.NewEq32(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := intern.NewEq(s)
+		if e32.Eq() != e {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, e, e32.Eq())
+		}
+		if e32.String() != s {
+			t.Fatalf("Expected %q but saw %q", s, e32)
+		}
+		if e32b, err := e.Eq32(); err != nil || e32b != e32 {
+			t.Fatalf("Failed to convert Eq %d to an Eq32", e)
+		}
+		if e32b, ok := intern.LookupEq32(s); !ok || e32b != e32 {
+			t.Fatalf("Failed to look up %q", s)
+		}
+	}
+	type pair struct {
+		a, b intern.Eq32
+	}
+	if sz := unsafe.Sizeof(pair{}); sz != 8 {
+		t.Fatalf("Expected a pair of Eq32s to occupy 8 bytes but saw %d", sz)
+	}
+}
+
+// TestEq32Range ensures that Eqs that do not fit in 32 bits are rejected.
+func TestEq32Range(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	const big = "Ozma of Oz"
+	if err := intern.ReserveEqs(map[string]intern.Eq{big: 1 << 40}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := intern.NewEq32(big)
+	var pe *intern.PkgError
+	if !errors.As(err, &pe) || pe.Code != intern.ErrOutOfRange || pe.Str != big {
+		t.Fatalf("Expected an out-of-range error but saw %v", err)
+	}
+	if _, ok := intern.LookupEq32(big); ok {
+		t.Fatalf("Unexpectedly found %q", big)
+	}
+	if _, err = intern.NewEq(big).Eq32(); err == nil {
+		t.Fatal("Unexpectedly converted a large Eq to an Eq32")
+	}
+}
+
+// TestEq32Marshal marshals Eq32s to JSON and to a gob and back and checks
+// that the outputs match the input.
+func TestEq32Marshal(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	iSyms := make([]intern.Eq32, len(ozChars))
+	for i, s := range ozChars {
+		var err error
+		iSyms[i], err = intern.NewEq32(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Encode the Eq32s as JSON and a gob.
+	j, err := json.Marshal(iSyms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = gob.NewEncoder(&buf).Encode(&iSyms); err != nil {
+		t.Fatal(err)
+	}
+
+	// Forget our entire mapping then decode the Eq32s.
+	intern.ForgetAllEqs()
+	var jSyms, gSyms []intern.Eq32
+	if err = json.Unmarshal(j, &jSyms); err != nil {
+		t.Fatal(err)
+	}
+	if err = gob.NewDecoder(&buf).Decode(&gSyms); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range ozChars {
+		if jSyms[i].String() != s {
+			t.Fatalf("Expected %q but saw %q", s, jSyms[i])
+		}
+		if gSyms[i].String() != s {
+			t.Fatalf("Expected %q but saw %q", s, gSyms[i])
+		}
+	}
+}
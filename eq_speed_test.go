@@ -3,7 +3,9 @@
 package intern_test
 
 import (
+	"fmt"
 	"math/rand"
+	"runtime"
 	"testing"
 
 	"github.com/spakin/intern"
@@ -108,3 +110,201 @@ func BenchmarkMergeEqMaps(b *testing.B) {
 		m3[k] = Empty{}
 	}
 }
+
+// benchmarkEqIndex measures the time needed to look up existing Eqs using a
+// given index.
+func benchmarkEqIndex(b *testing.B, idx intern.EqIndex, strs []string) {
+	intern.ForgetAllEqs()
+	defer intern.SetEqIndex(nil)
+	defer intern.ForgetAllEqs()
+	if err := intern.SetEqIndex(idx); err != nil {
+		b.Fatal(err)
+	}
+	intern.NewEqMulti(strs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e, _ := intern.LookupEq(strs[i%len(strs)])
+		Dummy += uint64(e)
+	}
+}
+
+// BenchmarkMapEqIndexRandom measures the time needed to look up random
+// strings using the default index.
+func BenchmarkMapEqIndexRandom(b *testing.B) {
+	benchmarkEqIndex(b, nil, generateRandomStrings(10000))
+}
+
+// BenchmarkDATrieEqIndexRandom measures the time needed to look up random
+// strings using a DATrie.
+func BenchmarkDATrieEqIndexRandom(b *testing.B) {
+	benchmarkEqIndex(b, &intern.DATrie{}, generateRandomStrings(10000))
+}
+
+// BenchmarkMapEqIndexSimilar measures the time needed to look up similar
+// strings using the default index.
+func BenchmarkMapEqIndexSimilar(b *testing.B) {
+	benchmarkEqIndex(b, nil, generateSimilarStrings(10000))
+}
+
+// BenchmarkDATrieEqIndexSimilar measures the time needed to look up similar
+// strings using a DATrie.
+func BenchmarkDATrieEqIndexSimilar(b *testing.B) {
+	benchmarkEqIndex(b, &intern.DATrie{}, generateSimilarStrings(10000))
+}
+
+// benchmarkContainsEqs measures the time needed to search for a substring of
+// an interned string with or without the search index.
+func benchmarkContainsEqs(b *testing.B, index bool) {
+	intern.ForgetAllEqs()
+	defer intern.EnableEqSearchIndex(false)
+	defer intern.ForgetAllEqs()
+	intern.EnableEqSearchIndex(index)
+	strs := generateRandomStrings(100000)
+	intern.NewEqMulti(strs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := strs[i%len(strs)]
+		Dummy += uint64(len(intern.ContainsEqs(s[len(s)/2:])))
+	}
+}
+
+// BenchmarkContainsEqsScan measures the time needed to search for a
+// substring by scanning all interned strings.
+func BenchmarkContainsEqsScan(b *testing.B) {
+	benchmarkContainsEqs(b, false)
+}
+
+// BenchmarkContainsEqsIndex measures the time needed to search for a
+// substring using the search index.
+func BenchmarkContainsEqsIndex(b *testing.B) {
+	benchmarkContainsEqs(b, true)
+}
+
+// BenchmarkMergeEqSets measures the performance of merging two EqSets.  It is
+// the EqSet analogue of BenchmarkMergeEqMaps.
+func BenchmarkMergeEqSets(b *testing.B) {
+	// Populate two sets.
+	intern.ForgetAllEqs()
+	prng := rand.New(rand.NewSource(2223)) // Constant for reproducibility
+	const sLen = 20                        // Symbol length in characters
+	s1 := &intern.EqSet{}
+	s2 := &intern.EqSet{}
+	for i := 0; i < b.N; i++ {
+		s := randomString(prng, sLen)
+		s1.Add(intern.NewEq(s))
+		s = randomString(prng, sLen)
+		s2.Add(intern.NewEq(s))
+	}
+
+	// Start the clock then merge the two sets into a third.
+	b.ResetTimer()
+	s3 := s1.Union(s2)
+	Dummy += uint64(s3.Len())
+}
+
+// benchmarkEqMapLookups prepares a list of Eqs and a function that records
+// a value for each Eq, then measures the time needed to look up every Eq.
+func benchmarkEqMapLookups(b *testing.B, set func(intern.Eq, int), get func(intern.Eq) int) {
+	intern.ForgetAllEqs()
+	const ns = 10000 // Number of Eqs
+	eqs := intern.NewEqMulti(generateRandomStrings(ns))
+	for i, e := range eqs {
+		set(e, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Dummy += uint64(get(eqs[i%ns]))
+	}
+}
+
+// BenchmarkGoMapEqLookups measures the time needed to look up values in a
+// map[Eq]int.
+func BenchmarkGoMapEqLookups(b *testing.B) {
+	m := make(map[intern.Eq]int)
+	benchmarkEqMapLookups(b,
+		func(e intern.Eq, v int) { m[e] = v },
+		func(e intern.Eq) int { return m[e] })
+}
+
+// BenchmarkEqMapLookups measures the time needed to look up values in an
+// EqMap[int].
+func BenchmarkEqMapLookups(b *testing.B) {
+	var m intern.EqMap[int]
+	benchmarkEqMapLookups(b,
+		func(e intern.Eq, v int) { m.Set(e, v) },
+		func(e intern.Eq) int { v, _ := m.Get(e); return v })
+}
+
+// BenchmarkMergeDenseEqMaps measures the performance of merging two
+// EqMaps.  It is the EqMap analogue of BenchmarkMergeEqMaps.
+func BenchmarkMergeDenseEqMaps(b *testing.B) {
+	// Populate two maps.
+	intern.ForgetAllEqs()
+	prng := rand.New(rand.NewSource(2223)) // Constant for reproducibility
+	const sLen = 20                        // Symbol length in characters
+	type Empty struct{}
+	var m1, m2 intern.EqMap[Empty]
+	for i := 0; i < b.N; i++ {
+		s := randomString(prng, sLen)
+		m1.Set(intern.NewEq(s), Empty{})
+		s = randomString(prng, sLen)
+		m2.Set(intern.NewEq(s), Empty{})
+	}
+
+	// Start the clock then merge the two maps into a third.
+	var m3 intern.EqMap[Empty]
+	b.ResetTimer()
+	m1.Each(func(k intern.Eq, v Empty) bool {
+		m3.Set(k, v)
+		return true
+	})
+	m2.Each(func(k intern.Eq, v Empty) bool {
+		m3.Set(k, v)
+		return true
+	})
+}
+
+// benchmarkShortEqs measures the memory consumed by short Eqs interned in a
+// given mode and the time needed to intern them again from multiple
+// goroutines at once.
+func benchmarkShortEqs(b *testing.B, mode intern.EqMode) {
+	useEqMode(b, mode)
+	defer useEqMode(b, intern.EqTableMode)
+
+	// Intern a set of short strings and measure the memory retained.
+	const ns = 100000 // Number of strings to intern
+	strs := make([]string, ns)
+	for i := range strs {
+		strs[i] = fmt.Sprintf("%07d", i)
+	}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	eqs := intern.NewEqMulti(strs)
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	// Measure the time needed to intern the strings concurrently.
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if intern.NewEq(strs[i%ns]) != eqs[i%ns] {
+				b.Error("Eqs changed")
+				return
+			}
+		}
+	})
+	b.ReportMetric((float64(after.HeapAlloc)-float64(before.HeapAlloc))/ns, "bytes/string")
+}
+
+// BenchmarkShortTableEqs measures the memory and concurrent NewEq
+// performance of short strings stored in the Eq table.
+func BenchmarkShortTableEqs(b *testing.B) {
+	benchmarkShortEqs(b, intern.EqTableMode)
+}
+
+// BenchmarkShortInlineEqs measures the memory and concurrent NewEq
+// performance of short strings packed into their Eqs.
+func BenchmarkShortInlineEqs(b *testing.B) {
+	benchmarkShortEqs(b, intern.EqInlineMode)
+}
@@ -0,0 +1,87 @@
+// This file provides support for replacing the index the Eq table uses to
+// look up strings.
+
+package intern
+
+// An EqIndex maps strings to Eqs.  By default, the Eq table looks up strings
+// using a Go map, but SetEqIndex can substitute any EqIndex, such as a
+// DATrie.  The package serializes all calls to an installed EqIndex's Insert
+// and Reset methods but may call Lookup from multiple goroutines at once.
+type EqIndex interface {
+	Lookup(s string) (Eq, bool) // Return the Eq associated with a string
+	Insert(s string, e Eq)      // Associate a string with an Eq
+	Reset()                     // Remove all strings
+}
+
+// lookupUnfrozen returns the symbol associated with a string in an unfrozen
+// state.  The caller must hold the state's lock.
+func (st *state) lookupUnfrozen(s string) (symbol, bool) {
+	if st.strIndex != nil {
+		e, ok := st.strIndex.Lookup(s)
+		return symbol(e), ok
+	}
+	sym, ok := st.strToSym[s]
+	return sym, ok
+}
+
+// setSym associates a string with a symbol in an unfrozen state.  The caller
+// must hold the state's write lock.
+func (st *state) setSym(s string, sym symbol) {
+	st.symToStr[sym] = s
+	if st.prefixTrie != nil {
+		st.prefixTrie.Insert(s, Eq(sym))
+	}
+	if st.ngrams != nil {
+		st.ngrams.insert(s, sym)
+	}
+	if st.near != nil {
+		st.near.insert(s, sym)
+	}
+	if st.strIndex != nil {
+		st.strIndex.Insert(s, Eq(sym))
+		return
+	}
+	st.strToSym[s] = sym
+}
+
+// strMap returns a map from strings to symbols for an unfrozen state,
+// constructing one if the state uses an EqIndex instead.  The caller must
+// hold the state's lock.
+func (st *state) strMap() map[string]symbol {
+	if st.strToSym != nil {
+		return st.strToSym
+	}
+	strToSym := make(map[string]symbol, len(st.symToStr))
+	for sym, s := range st.symToStr {
+		strToSym[s] = sym
+	}
+	return strToSym
+}
+
+// SetEqIndex replaces the index the Eq table uses to look up strings.  All
+// existing Eqs are inserted into the new index.  Passing nil restores the
+// default, a Go map.  The index should be empty, and the program should not
+// call its methods while it is installed.  ForgetAllEqs and FreezeEqs empty
+// the index but leave it installed.  SetEqIndex returns an error if the Eq
+// table is frozen.
+func SetEqIndex(idx EqIndex) error {
+	eq.Lock()
+	defer eq.Unlock()
+	if err := eq.checkFrozen("", "Eq"); err != nil {
+		return err
+	}
+	if idx == nil {
+		eq.strToSym = eq.strMap()
+		eq.strIndex = nil
+		return nil
+	}
+	for sym, s := range eq.symToStr {
+		idx.Insert(s, Eq(sym))
+	}
+	eq.strIndex = idx
+	eq.strToSym = nil
+	if _, ok := idx.(prefixIndex); ok {
+		eq.prefixTrie = nil // The new index supersedes the prefix trie.
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// This file provides EqMap, a map keyed by Eqs that is backed by a slice.
+
+package intern
+
+import (
+	"encoding/json"
+	"math/bits"
+	"sort"
+)
+
+// eqMapSlack is the number of Eqs beyond twice its current capacity that an
+// EqMap's slice is allowed to grow to accommodate.  Larger Eqs are stored in
+// a Go map instead.
+const eqMapSlack = 1024
+
+// An EqMap is a map from Eqs to values of type V.  Because Eqs are normally
+// small, densely packed integers, an EqMap stores values in a slice indexed
+// by Eq, with a bitmap recording which Eqs are present.  Getting and setting
+// a value therefore require no hashing.  Eqs much larger than the others, such
+// as those reserved with ReserveEqs, spill into an ordinary Go map.  The zero
+// value is an empty map ready to use.  An EqMap is not safe for concurrent
+// modification.
+//
+// Only Eqs assigned sequentially in EqTableMode (see SetEqMode) are small.
+// Inline Eqs in EqInlineMode have their high bit set, and hashed Eqs in
+// EqHashMode are spread over 63 bits, so all such Eqs spill into the Go map,
+// and an EqMap holding them is no faster than a map[Eq]V.
+type EqMap[V any] struct {
+	vals     []V      // Value associated with each Eq
+	present  []uint64 // Bitmap of the Eqs present in vals
+	n        int      // Number of Eqs present in vals
+	overflow map[Eq]V // Values associated with Eqs too large for vals
+}
+
+// has says whether an Eq is present in an EqMap's slice.
+func (m *EqMap[V]) has(e Eq) bool {
+	return e < Eq(len(m.vals)) && m.present[e/64]&(1<<(e%64)) != 0
+}
+
+// grow extends an EqMap's slice to include a given Eq, moving any values
+// from the overflow map that now fit in the slice.
+func (m *EqMap[V]) grow(e Eq) {
+	n := 2 * len(m.vals)
+	if n <= int(e) {
+		n = int(e) + 1
+	}
+	n = (n + 63) &^ 63
+	vals := make([]V, n)
+	copy(vals, m.vals)
+	present := make([]uint64, n/64)
+	copy(present, m.present)
+	m.vals, m.present = vals, present
+	for k, v := range m.overflow {
+		if k < Eq(n) {
+			delete(m.overflow, k)
+			m.Set(k, v)
+		}
+	}
+}
+
+// Get returns the value associated with an Eq.  The second return value
+// indicates whether the Eq is present.
+func (m *EqMap[V]) Get(e Eq) (V, bool) {
+	if m.has(e) {
+		return m.vals[e], true
+	}
+	v, ok := m.overflow[e]
+	return v, ok
+}
+
+// Set associates a value with an Eq.
+func (m *EqMap[V]) Set(e Eq, v V) {
+	if e >= Eq(len(m.vals)) {
+		if e >= Eq(2*len(m.vals)+eqMapSlack) {
+			if m.overflow == nil {
+				m.overflow = make(map[Eq]V)
+			}
+			m.overflow[e] = v
+			return
+		}
+		m.grow(e)
+	}
+	m.vals[e] = v
+	if !m.has(e) {
+		m.present[e/64] |= 1 << (e % 64)
+		m.n++
+	}
+}
+
+// Delete removes an Eq and its value from an EqMap.
+func (m *EqMap[V]) Delete(e Eq) {
+	if m.has(e) {
+		var zero V
+		m.vals[e] = zero
+		m.present[e/64] &^= 1 << (e % 64)
+		m.n--
+		return
+	}
+	delete(m.overflow, e)
+}
+
+// Len returns the number of Eqs in an EqMap.
+func (m *EqMap[V]) Len() int {
+	return m.n + len(m.overflow)
+}
+
+// Each calls a function on each Eq in an EqMap and its value, in increasing
+// order of Eq, until the function returns false.  The function must not
+// modify the map.
+func (m *EqMap[V]) Each(f func(e Eq, v V) bool) {
+	for w, word := range m.present {
+		for word != 0 {
+			e := Eq(w*64) + Eq(bits.TrailingZeros64(word))
+			if !f(e, m.vals[e]) {
+				return
+			}
+			word &= word - 1
+		}
+	}
+	if len(m.overflow) == 0 {
+		return
+	}
+	keys := make([]Eq, 0, len(m.overflow))
+	for k := range m.overflow {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, k := range keys {
+		if !f(k, m.overflow[k]) {
+			return
+		}
+	}
+}
+
+// MarshalJSON converts an EqMap to a JSON object whose keys are the Eqs'
+// strings.  With this method, EqMap implements the json.Marshaler interface.
+func (m *EqMap[V]) MarshalJSON() ([]byte, error) {
+	sm := make(map[string]V, m.Len())
+	m.Each(func(e Eq, v V) bool {
+		sm[e.String()] = v
+		return true
+	})
+	return json.Marshal(sm)
+}
+
+// UnmarshalJSON converts a JSON object to an EqMap, interning each key.  With
+// this method, EqMap implements the json.Unmarshaler interface.
+func (m *EqMap[V]) UnmarshalJSON(data []byte) error {
+	var sm map[string]V
+	if err := json.Unmarshal(data, &sm); err != nil {
+		return err
+	}
+	var r EqMap[V]
+	for s, v := range sm {
+		e, err := eq.newEq(s)
+		if err != nil {
+			return err
+		}
+		r.Set(e, v)
+	}
+	*m = r
+	return nil
+}
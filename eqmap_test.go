@@ -0,0 +1,95 @@
+// This file provides unit tests for EqMap.
+
+package intern_test
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// TestEqMap compares EqMap operations to the equivalent map operations.
+func TestEqMap(t *testing.T) {
+	prng := rand.New(rand.NewSource(6565)) // Constant for reproducibility
+	var em intern.EqMap[int]
+	m := make(map[intern.Eq]int)
+	for i := 0; i < 50000; i++ {
+		var e intern.Eq
+		switch i % 10 {
+		case 0:
+			e = intern.Eq(prng.Uint64()) // Overflow
+		default:
+			e = intern.Eq(prng.Intn(i + 100)) // Dense
+		}
+		switch prng.Intn(4) {
+		case 0:
+			em.Delete(e)
+			delete(m, e)
+		default:
+			em.Set(e, i)
+			m[e] = i
+		}
+	}
+
+	// Compare the contents of the two maps.
+	if em.Len() != len(m) {
+		t.Fatalf("Expected %d Eqs but saw %d", len(m), em.Len())
+	}
+	for e, v := range m {
+		if v2, ok := em.Get(e); !ok || v2 != v {
+			t.Fatalf("Expected Eq %d to map to %d but saw %d", e, v, v2)
+		}
+	}
+	if _, ok := em.Get(^intern.Eq(0)); ok {
+		t.Fatal("Unexpectedly found the largest Eq")
+	}
+	n := 0
+	var prev intern.Eq
+	em.Each(func(e intern.Eq, v int) bool {
+		if n > 0 && e <= prev {
+			t.Fatalf("Eq %d follows Eq %d", e, prev)
+		}
+		if m[e] != v {
+			t.Fatalf("Expected Eq %d to map to %d but saw %d", e, m[e], v)
+		}
+		prev = e
+		n++
+		return true
+	})
+	if n != len(m) {
+		t.Fatalf("Expected to visit %d Eqs but saw %d", len(m), n)
+	}
+}
+
+// TestEqMapJSON ensures that an EqMap can be marshaled and unmarshaled as
+// JSON with strings as keys.
+func TestEqMapJSON(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	var em intern.EqMap[int]
+	for i, s := range ozChars[:10] {
+		em.Set(intern.NewEq(s), i)
+	}
+	data, err := json.Marshal(&em)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sm map[string]int
+	if err = json.Unmarshal(data, &sm); err != nil || sm[ozChars[3]] != 3 {
+		t.Fatalf("Unexpected JSON encoding %s", data)
+	}
+	var em2 intern.EqMap[int]
+	if err = json.Unmarshal(data, &em2); err != nil {
+		t.Fatal(err)
+	}
+	if em2.Len() != 10 {
+		t.Fatalf("Expected 10 Eqs but saw %d", em2.Len())
+	}
+	for i, s := range ozChars[:10] {
+		if v, ok := em2.Get(intern.NewEq(s)); !ok || v != i {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, i, v)
+		}
+	}
+}
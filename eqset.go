@@ -0,0 +1,446 @@
+// This file provides EqSet, a compressed bitmap representing a set of Eqs.
+
+package intern
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+// eqArrayMax is the largest number of Eqs an eqContainer stores as an array.
+// Beyond that, a bitmap is smaller.
+const eqArrayMax = 4096
+
+// eqBitmapWords is the number of words in an eqContainer's bitmap.
+const eqBitmapWords = 1 << 16 / 64
+
+// An eqContainer holds the low 16 bits of the Eqs in an EqSet that share
+// the same high 48 bits.  Following the Roaring bitmap format, it uses a
+// sorted array when it holds few Eqs and a bitmap when it holds many.
+type eqContainer struct {
+	array  []uint16 // Sorted low bits, or nil if bitmap is used
+	bitmap []uint64 // Bitmap of low bits, or nil if array is used
+	n      int      // Number of Eqs held
+}
+
+// contains says whether an eqContainer holds a given low 16 bits.
+func (c *eqContainer) contains(lo uint16) bool {
+	if c.bitmap != nil {
+		return c.bitmap[lo/64]&(1<<(lo%64)) != 0
+	}
+	i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= lo })
+	return i < len(c.array) && c.array[i] == lo
+}
+
+// add adds low 16 bits to an eqContainer.
+func (c *eqContainer) add(lo uint16) {
+	if c.bitmap != nil {
+		w, b := lo/64, uint64(1)<<(lo%64)
+		if c.bitmap[w]&b == 0 {
+			c.bitmap[w] |= b
+			c.n++
+		}
+		return
+	}
+	i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= lo })
+	if i < len(c.array) && c.array[i] == lo {
+		return
+	}
+	if len(c.array) == eqArrayMax {
+		c.bitmap = c.words()
+		c.array = nil
+		c.add(lo)
+		return
+	}
+	c.array = append(c.array, 0)
+	copy(c.array[i+1:], c.array[i:])
+	c.array[i] = lo
+	c.n++
+}
+
+// remove removes low 16 bits from an eqContainer.
+func (c *eqContainer) remove(lo uint16) {
+	if c.bitmap != nil {
+		w, b := lo/64, uint64(1)<<(lo%64)
+		if c.bitmap[w]&b != 0 {
+			c.bitmap[w] &^= b
+			c.n--
+		}
+		if c.n <= eqArrayMax/2 {
+			*c = *newEqContainer(c.bitmap)
+		}
+		return
+	}
+	i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= lo })
+	if i < len(c.array) && c.array[i] == lo {
+		c.array = append(c.array[:i], c.array[i+1:]...)
+		c.n--
+	}
+}
+
+// words returns an eqContainer's contents as a newly allocated bitmap.
+func (c *eqContainer) words() []uint64 {
+	ws := make([]uint64, eqBitmapWords)
+	if c.bitmap != nil {
+		copy(ws, c.bitmap)
+		return ws
+	}
+	for _, lo := range c.array {
+		ws[lo/64] |= 1 << (lo % 64)
+	}
+	return ws
+}
+
+// each calls a function on each of an eqContainer's low 16 bits in
+// increasing order.  It returns false if the function asked to stop.
+func (c *eqContainer) each(f func(lo uint16) bool) bool {
+	if c.bitmap == nil {
+		for _, lo := range c.array {
+			if !f(lo) {
+				return false
+			}
+		}
+		return true
+	}
+	for w, word := range c.bitmap {
+		for word != 0 {
+			b := bits.TrailingZeros64(word)
+			if !f(uint16(w*64 + b)) {
+				return false
+			}
+			word &= word - 1
+		}
+	}
+	return true
+}
+
+// newEqContainer constructs an eqContainer from a bitmap, choosing whichever
+// representation is smaller.  It may reuse the bitmap.
+func newEqContainer(ws []uint64) *eqContainer {
+	n := 0
+	for _, w := range ws {
+		n += bits.OnesCount64(w)
+	}
+	if n > eqArrayMax {
+		return &eqContainer{bitmap: ws, n: n}
+	}
+	c := &eqContainer{array: make([]uint16, 0, n), n: n}
+	for w, word := range ws {
+		for word != 0 {
+			c.array = append(c.array, uint16(w*64+bits.TrailingZeros64(word)))
+			word &= word - 1
+		}
+	}
+	return c
+}
+
+// A setOp is an operation on two sets.
+type setOp int
+
+// These constants represent the operations on two sets.
+const (
+	opUnion      setOp = iota // Elements in either set
+	opIntersect               // Elements in both sets
+	opDifference              // Elements in the first set but not the second
+)
+
+// apply applies a setOp to two words of a bitmap.
+func (op setOp) apply(a, b uint64) uint64 {
+	switch op {
+	case opUnion:
+		return a | b
+	case opIntersect:
+		return a & b
+	default:
+		return a &^ b
+	}
+}
+
+// combine applies a setOp to two eqContainers and returns the result, or nil
+// if the result is empty.  Either container may be nil, representing an
+// empty container.
+func combine(c1, c2 *eqContainer, op setOp) *eqContainer {
+	// Merge two arrays directly.
+	if (c1 == nil || c1.bitmap == nil) && (c2 == nil || c2.bitmap == nil) {
+		var a, b []uint16
+		if c1 != nil {
+			a = c1.array
+		}
+		if c2 != nil {
+			b = c2.array
+		}
+		c := &eqContainer{}
+		i, j := 0, 0
+		for i < len(a) || j < len(b) {
+			switch {
+			case j == len(b) || (i < len(a) && a[i] < b[j]):
+				if op != opIntersect {
+					c.add(a[i])
+				}
+				i++
+			case i == len(a) || b[j] < a[i]:
+				if op == opUnion {
+					c.add(b[j])
+				}
+				j++
+			default:
+				if op != opDifference {
+					c.add(a[i])
+				}
+				i++
+				j++
+			}
+		}
+		if c.n == 0 {
+			return nil
+		}
+		return c
+	}
+
+	// Combine bitmaps word by word.
+	var ws1, ws2 []uint64
+	if c1 != nil {
+		ws1 = c1.words()
+	} else {
+		ws1 = make([]uint64, eqBitmapWords)
+	}
+	if c2 != nil {
+		ws2 = c2.words()
+	} else {
+		ws2 = make([]uint64, eqBitmapWords)
+	}
+	for i := range ws1 {
+		ws1[i] = op.apply(ws1[i], ws2[i])
+	}
+	c := newEqContainer(ws1)
+	if c.n == 0 {
+		return nil
+	}
+	return c
+}
+
+// An EqSet is a set of Eqs.  Because Eqs are normally small, densely packed
+// integers, an EqSet stores them as a compressed bitmap in the style of
+// Roaring bitmaps: Eqs are grouped by their upper 48 bits, and each group is
+// stored as either a sorted array or a bitmap of the lower 16 bits.  This is
+// typically far smaller and faster than a map[Eq]struct{}.  The zero value
+// is an empty set ready to use.  An EqSet is not safe for concurrent
+// modification.
+type EqSet struct {
+	keys  []uint64       // Upper 48 bits of each container's Eqs, sorted
+	conts []*eqContainer // Container for each key
+}
+
+// NewEqSet returns a new EqSet containing the given Eqs.
+func NewEqSet(eqs ...Eq) *EqSet {
+	s := &EqSet{}
+	for _, e := range eqs {
+		s.Add(e)
+	}
+	return s
+}
+
+// find returns the index of the container for a given key or the index at
+// which it would be inserted.
+func (s *EqSet) find(key uint64) (int, bool) {
+	i := sort.Search(len(s.keys), func(i int) bool { return s.keys[i] >= key })
+	return i, i < len(s.keys) && s.keys[i] == key
+}
+
+// Add adds an Eq to a set.
+func (s *EqSet) Add(e Eq) {
+	key, lo := uint64(e)>>16, uint16(e)
+	i, ok := s.find(key)
+	if !ok {
+		s.keys = append(s.keys, 0)
+		copy(s.keys[i+1:], s.keys[i:])
+		s.keys[i] = key
+		s.conts = append(s.conts, nil)
+		copy(s.conts[i+1:], s.conts[i:])
+		s.conts[i] = &eqContainer{}
+	}
+	s.conts[i].add(lo)
+}
+
+// Remove removes an Eq from a set.
+func (s *EqSet) Remove(e Eq) {
+	i, ok := s.find(uint64(e) >> 16)
+	if !ok {
+		return
+	}
+	s.conts[i].remove(uint16(e))
+	if s.conts[i].n == 0 {
+		s.keys = append(s.keys[:i], s.keys[i+1:]...)
+		s.conts = append(s.conts[:i], s.conts[i+1:]...)
+	}
+}
+
+// Contains says whether a set contains a given Eq.
+func (s *EqSet) Contains(e Eq) bool {
+	i, ok := s.find(uint64(e) >> 16)
+	return ok && s.conts[i].contains(uint16(e))
+}
+
+// Len returns the number of Eqs in a set.
+func (s *EqSet) Len() int {
+	n := 0
+	for _, c := range s.conts {
+		n += c.n
+	}
+	return n
+}
+
+// Each calls a function on each Eq in a set, in increasing order of Eq, until
+// the function returns false.  The function must not modify the set.
+func (s *EqSet) Each(f func(e Eq) bool) {
+	for i, c := range s.conts {
+		hi := Eq(s.keys[i] << 16)
+		if !c.each(func(lo uint16) bool { return f(hi | Eq(lo)) }) {
+			return
+		}
+	}
+}
+
+// Eqs returns all Eqs in a set in increasing order.
+func (s *EqSet) Eqs() []Eq {
+	eqs := make([]Eq, 0, s.Len())
+	s.Each(func(e Eq) bool {
+		eqs = append(eqs, e)
+		return true
+	})
+	return eqs
+}
+
+// merge applies a setOp to corresponding containers of two sets and returns
+// the result as a new set.
+func (s *EqSet) merge(o *EqSet, op setOp) *EqSet {
+	r := &EqSet{}
+	i, j := 0, 0
+	for i < len(s.keys) || j < len(o.keys) {
+		var key uint64
+		var c1, c2 *eqContainer
+		switch {
+		case j == len(o.keys) || (i < len(s.keys) && s.keys[i] < o.keys[j]):
+			key, c1 = s.keys[i], s.conts[i]
+			i++
+		case i == len(s.keys) || o.keys[j] < s.keys[i]:
+			key, c2 = o.keys[j], o.conts[j]
+			j++
+		default:
+			key, c1, c2 = s.keys[i], s.conts[i], o.conts[j]
+			i++
+			j++
+		}
+		if (op == opIntersect && c2 == nil) || (op != opUnion && c1 == nil) {
+			continue
+		}
+		if c := combine(c1, c2, op); c != nil {
+			r.keys = append(r.keys, key)
+			r.conts = append(r.conts, c)
+		}
+	}
+	return r
+}
+
+// Union returns a new set containing every Eq in either of two sets.
+func (s *EqSet) Union(o *EqSet) *EqSet {
+	return s.merge(o, opUnion)
+}
+
+// Intersect returns a new set containing every Eq in both of two sets.
+func (s *EqSet) Intersect(o *EqSet) *EqSet {
+	return s.merge(o, opIntersect)
+}
+
+// Difference returns a new set containing every Eq in s that is not in o.
+func (s *EqSet) Difference(o *EqSet) *EqSet {
+	return s.merge(o, opDifference)
+}
+
+// strings returns the strings associated with the Eqs in a set, in
+// increasing order of Eq.
+func (s *EqSet) strings() []string {
+	strs := make([]string, 0, s.Len())
+	s.Each(func(e Eq) bool {
+		strs = append(strs, e.String())
+		return true
+	})
+	return strs
+}
+
+// fromStrings replaces the contents of a set with the Eqs of a list of
+// strings.
+func (s *EqSet) fromStrings(strs []string) error {
+	r := EqSet{}
+	for _, str := range strs {
+		e, err := eq.newEq(str)
+		if err != nil {
+			return err
+		}
+		r.Add(e)
+	}
+	*s = r
+	return nil
+}
+
+// MarshalBinary converts an EqSet to a slice of bytes.  Because Eqs are
+// meaningful only within a single process, the encoding contains the Eqs'
+// strings rather than the Eqs themselves.  With this method, EqSet
+// implements the encoding.BinaryMarshaler interface.
+func (s *EqSet) MarshalBinary() ([]byte, error) {
+	strs := s.strings()
+	buf := binary.AppendUvarint(nil, uint64(len(strs)))
+	for _, str := range strs {
+		buf = binary.AppendUvarint(buf, uint64(len(str)))
+		buf = append(buf, str...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary converts a slice of bytes produced by MarshalBinary back to
+// an EqSet, interning each string it contains.  With this method, EqSet
+// implements the encoding.BinaryUnmarshaler interface.
+func (s *EqSet) UnmarshalBinary(data []byte) error {
+	br := bytes.NewReader(data)
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	if n > uint64(len(data)) {
+		return io.ErrUnexpectedEOF
+	}
+	strs := make([]string, n)
+	for i := range strs {
+		ln, err := binary.ReadUvarint(br)
+		if err != nil || ln > uint64(len(data)) {
+			return io.ErrUnexpectedEOF
+		}
+		b := make([]byte, ln)
+		if _, err = io.ReadFull(br, b); err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		strs[i] = string(b)
+	}
+	return s.fromStrings(strs)
+}
+
+// MarshalJSON converts an EqSet to a JSON array of the Eqs' strings.  With
+// this method, EqSet implements the json.Marshaler interface.
+func (s *EqSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.strings())
+}
+
+// UnmarshalJSON converts a JSON array of strings to an EqSet, interning each
+// string.  With this method, EqSet implements the json.Unmarshaler
+// interface.
+func (s *EqSet) UnmarshalJSON(data []byte) error {
+	var strs []string
+	if err := json.Unmarshal(data, &strs); err != nil {
+		return err
+	}
+	return s.fromStrings(strs)
+}
@@ -0,0 +1,145 @@
+// This file provides unit tests for EqSet.
+
+package intern_test
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// expectSet fails a test if an EqSet does not contain exactly the members of
+// a map.
+func expectSet(t *testing.T, what string, s *intern.EqSet, m map[intern.Eq]bool) {
+	t.Helper()
+	if s.Len() != len(m) {
+		t.Fatalf("%s: expected %d Eqs but saw %d", what, len(m), s.Len())
+	}
+	var prev intern.Eq
+	for i, e := range s.Eqs() {
+		if !m[e] {
+			t.Fatalf("%s: unexpected Eq %d", what, e)
+		}
+		if i > 0 && e <= prev {
+			t.Fatalf("%s: Eq %d follows Eq %d", what, e, prev)
+		}
+		if !s.Contains(e) {
+			t.Fatalf("%s: failed to find Eq %d", what, e)
+		}
+		prev = e
+	}
+}
+
+// randomEqSet returns an EqSet and a map containing the same random Eqs.  The
+// Eqs are drawn from both sparse and dense ranges.
+func randomEqSet(prng *rand.Rand, n int) (*intern.EqSet, map[intern.Eq]bool) {
+	s := &intern.EqSet{}
+	m := make(map[intern.Eq]bool, n)
+	for i := 0; i < n; i++ {
+		var e intern.Eq
+		switch i % 3 {
+		case 0:
+			e = intern.Eq(prng.Intn(20000) + 1) // Dense
+		case 1:
+			e = intern.Eq(prng.Intn(1<<20) + 1) // Sparse
+		default:
+			e = intern.Eq(prng.Uint64())
+		}
+		s.Add(e)
+		m[e] = true
+	}
+	return s, m
+}
+
+// TestEqSet compares EqSet operations to the equivalent map operations.
+func TestEqSet(t *testing.T) {
+	prng := rand.New(rand.NewSource(6464)) // Constant for reproducibility
+	s1, m1 := randomEqSet(prng, 30000)
+	s2, m2 := randomEqSet(prng, 20000)
+	expectSet(t, "s1", s1, m1)
+	expectSet(t, "s2", s2, m2)
+
+	// Test the set operations.
+	union := make(map[intern.Eq]bool)
+	inter := make(map[intern.Eq]bool)
+	diff := make(map[intern.Eq]bool)
+	for e := range m1 {
+		union[e] = true
+		if m2[e] {
+			inter[e] = true
+		} else {
+			diff[e] = true
+		}
+	}
+	for e := range m2 {
+		union[e] = true
+	}
+	expectSet(t, "union", s1.Union(s2), union)
+	expectSet(t, "intersection", s1.Intersect(s2), inter)
+	expectSet(t, "difference", s1.Difference(s2), diff)
+
+	// Test removal, including enough removals to turn bitmaps back into
+	// arrays.
+	for e := range m1 {
+		if prng.Intn(4) != 0 {
+			s1.Remove(e)
+			delete(m1, e)
+		}
+	}
+	s1.Remove(12345678)
+	expectSet(t, "removal", s1, m1)
+
+	// Ensure that Each stops when asked to.
+	n := 0
+	s1.Each(func(intern.Eq) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Fatalf("Expected Each to stop after 10 Eqs but saw %d", n)
+	}
+}
+
+// TestEqSetMarshal ensures that an EqSet can be marshaled and unmarshaled as
+// binary data and as JSON.
+func TestEqSetMarshal(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	s := intern.NewEqSet(intern.NewEqMulti(ozChars[:20])...)
+	m := make(map[intern.Eq]bool)
+	s.Each(func(e intern.Eq) bool {
+		m[e] = true
+		return true
+	})
+
+	// Round-trip the set through a binary encoding.
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s2 intern.EqSet
+	if err = s2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	expectSet(t, "binary", &s2, m)
+	if err = s2.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatal("Failed to reject truncated data")
+	}
+
+	// Round-trip the set through JSON.  The JSON should contain strings.
+	data, err = json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var strs []string
+	if err = json.Unmarshal(data, &strs); err != nil || len(strs) != 20 || strs[0] != ozChars[0] {
+		t.Fatalf("Unexpected JSON encoding %s", data)
+	}
+	var s3 intern.EqSet
+	if err = json.Unmarshal(data, &s3); err != nil {
+		t.Fatal(err)
+	}
+	expectSet(t, "JSON", &s3, m)
+}
@@ -0,0 +1,262 @@
+// This file provides support for freezing a symbol table so that it can be
+// read without locking.
+
+package intern
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// An LGEStorage selects how FreezeLGEsWith stores a frozen LGE table.
+type LGEStorage int
+
+// These constants represent the available storage formats for frozen LGE
+// tables.
+const (
+	LGEHashStorage       LGEStorage = iota // Minimal perfect hash plus a map (the default)
+	LGEFSTStorage                          // Finite-state transducer
+	LGEFrontCodedStorage                   // Front-coded blocks of sorted strings
+)
+
+// A frozenStore is the read-only storage behind a frozen table.  Every
+// frozenStore can enumerate its strings in sorted order by rank, where a
+// string's rank is the number of strings in the table that precede it.
+type frozenStore interface {
+	lookup(s string) (symbol, bool)         // Symbol associated with a string
+	str(sym symbol) (string, bool)          // String associated with a symbol
+	size() int                              // Number of strings
+	symAt(r int) symbol                     // Symbol of the string with a given rank
+	prefixRanks(prefix string) (lo, hi int) // Ranks of the strings beginning with a prefix
+	index() *mph                            // Minimal perfect hash of all mappings
+	storage() LGEStorage                    // Form of storage
+}
+
+// A frozenTable is an immutable view of a symbol table's mappings.  Because
+// it never changes, it can be read without acquiring any locks.
+type frozenTable struct {
+	frozenStore
+}
+
+// A hashStore is a frozenStore that looks up strings using a minimal perfect
+// hash, which saves memory relative to a Go map, and symbols using a map.
+type hashStore struct {
+	symToStr  map[symbol]string // Mapping from symbols to strings
+	mph       *mph              // Mapping from strings to symbols
+	orderOnce sync.Once         // Guard for computing order
+	order     []int32           // Slots of mph in string order
+}
+
+// lookup returns the symbol associated with a string in a hashStore.
+func (hs *hashStore) lookup(s string) (symbol, bool) {
+	return hs.mph.lookup(s)
+}
+
+// str returns the string associated with a symbol in a hashStore.
+func (hs *hashStore) str(sym symbol) (string, bool) {
+	s, ok := hs.symToStr[sym]
+	return s, ok
+}
+
+// size returns the number of strings in a hashStore.
+func (hs *hashStore) size() int {
+	return len(hs.mph.keys)
+}
+
+// sorted returns the slots of a hashStore's hash in string order.  The order
+// is computed on first use because Eq tables rarely need it.
+func (hs *hashStore) sorted() []int32 {
+	hs.orderOnce.Do(func() {
+		keys := hs.mph.keys
+		hs.order = make([]int32, len(keys))
+		for i := range hs.order {
+			hs.order[i] = int32(i)
+		}
+		sort.Slice(hs.order, func(i, j int) bool {
+			return keys[hs.order[i]] < keys[hs.order[j]]
+		})
+	})
+	return hs.order
+}
+
+// symAt returns the symbol of the string with a given rank in a hashStore.
+func (hs *hashStore) symAt(r int) symbol {
+	return hs.mph.syms[hs.sorted()[r]]
+}
+
+// prefixRanks returns the half-open range of ranks of the strings in a
+// hashStore that begin with a given prefix.
+func (hs *hashStore) prefixRanks(prefix string) (lo, hi int) {
+	order, keys := hs.sorted(), hs.mph.keys
+	lo = sort.Search(len(order), func(i int) bool {
+		return keys[order[i]] >= prefix
+	})
+	hi = lo + sort.Search(len(order)-lo, func(i int) bool {
+		return !strings.HasPrefix(keys[order[lo+i]], prefix)
+	})
+	return lo, hi
+}
+
+// index returns a hashStore's minimal perfect hash.
+func (hs *hashStore) index() *mph {
+	return hs.mph
+}
+
+// storage reports that a hashStore uses LGEHashStorage.
+func (hs *hashStore) storage() LGEStorage {
+	return LGEHashStorage
+}
+
+// newHashStore constructs a hashStore from a pair of maps.
+func newHashStore(symToStr map[symbol]string, strToSym map[string]symbol) *hashStore {
+	return &hashStore{
+		symToStr: symToStr,
+		mph:      newMPH(strToSym),
+	}
+}
+
+// sortedEntries returns all of a state's strings in sorted order along with
+// their symbols.  The caller must hold the state's lock.
+func (st *state) sortedEntries() ([]string, []symbol) {
+	if ft := st.frozenTable(); ft != nil {
+		n := ft.size()
+		strs := make([]string, n)
+		syms := make([]symbol, n)
+		for r := range syms {
+			syms[r] = ft.symAt(r)
+			strs[r], _ = ft.str(syms[r])
+		}
+		return strs, syms
+	}
+	strToSym := st.strMap()
+	strs := make([]string, 0, len(strToSym))
+	for s := range strToSym {
+		strs = append(strs, s)
+	}
+	sort.Strings(strs)
+	syms := make([]symbol, len(strs))
+	for i, s := range strs {
+		syms[i] = strToSym[s]
+	}
+	return strs, syms
+}
+
+// freeze makes a state's current mappings read-only using a given form of
+// storage.  Once frozen, the mappings can be read without locking.  Freezing
+// a frozen state converts it to the new form of storage.  The caller must
+// hold the state's write lock.
+func (st *state) freeze(storage LGEStorage) {
+	if ft := st.frozenTable(); ft != nil && ft.storage() == storage {
+		return
+	}
+	var fs frozenStore
+	switch {
+	case storage == LGEFSTStorage:
+		fs = newFSTStore(st.sortedEntries())
+	case storage == LGEFrontCodedStorage:
+		fs = newFrontStore(st.sortedEntries())
+	case st.frozenTable() != nil:
+		strs, syms := st.sortedEntries()
+		symToStr := make(map[symbol]string, len(strs))
+		strToSym := make(map[string]symbol, len(strs))
+		for i, s := range strs {
+			symToStr[syms[i]] = s
+			strToSym[s] = syms[i]
+		}
+		fs = newHashStore(symToStr, strToSym)
+	default:
+		fs = newHashStore(st.symToStr, st.strMap())
+	}
+	st.frozen.Store(&frozenTable{fs})
+
+	// The frozen table supersedes the mutable mappings.
+	st.symToStr = nil
+	st.strToSym = nil
+	st.tree = nil
+	st.blocks = nil
+	st.pending = nil // Release strings retained by the backing array.
+	if st.strIndex != nil {
+		st.strIndex.Reset()
+	}
+}
+
+// lookup returns the symbol associated with a string, whether or not the
+// state is frozen.  The caller must hold the state's lock.
+func (st *state) lookup(s string) (symbol, bool) {
+	if ft := st.frozen.Load(); ft != nil {
+		return ft.lookup(s)
+	}
+	return st.lookupUnfrozen(s)
+}
+
+// frozenTable returns a state's frozen mappings or nil if the state is not
+// frozen.  It does not require the caller to hold any locks.
+func (st *state) frozenTable() *frozenTable {
+	return st.frozen.Load()
+}
+
+// checkFrozen returns an error if a state is frozen.  The caller must hold
+// the state's lock.
+func (st *state) checkFrozen(s string, ty string) error {
+	if st.frozen.Load() == nil {
+		return nil
+	}
+	return &PkgError{
+		Code: ErrFrozen,
+		Str:  s,
+		msg:  fmt.Sprintf("Unable to modify the %s table; it is frozen", ty),
+	}
+}
+
+// FreezeEqs makes the current set of Eqs read-only.  Afterwards, NewEq and
+// String no longer acquire any locks, which improves their throughput when
+// called from many goroutines at once.  However, NewEq panics and LookupEq
+// reports failure when given a string that was not already interned; and
+// ReserveEqs, ReserveEqRange, LoadEqReservations, and UnmarshalText return an
+// error.  ForgetAllEqs discards all Eqs and thaws the table.
+func FreezeEqs() {
+	eq.Lock()
+	if eq.frozenTable() == nil {
+		eq.freeze(LGEHashStorage)
+	}
+	eq.Unlock()
+}
+
+// FreezeLGEs makes the current set of LGEs read-only.  Afterwards, NewLGE,
+// NewLGEMulti, LookupLGE, and String no longer acquire any locks, which
+// improves their throughput when called from many goroutines at once.
+// However, NewLGE, NewLGEMulti, RemapAllLGEs, ReserveLGEs, and UnmarshalText
+// return an error when given a string that was not already interned.
+// ForgetAllLGEs discards all LGEs and thaws the table.  FreezeLGEs first
+// assigns LGEs to all strings passed to PreLGE, which can fail.  FreezeLGEs
+// is equivalent to FreezeLGEsWith(LGEHashStorage).
+func FreezeLGEs() error {
+	return FreezeLGEsWith(LGEHashStorage)
+}
+
+// FreezeLGEsWith performs the same operation as FreezeLGEs but lets the
+// caller choose how the frozen table is stored.  LGEHashStorage looks up
+// strings using a minimal perfect hash and is the fastest.  LGEFSTStorage
+// stores all strings in a minimal acyclic finite-state transducer that maps
+// each string to its rank in sorted order.  Strings sharing a prefix or a
+// suffix share states in the transducer, so this can use substantially less
+// memory for large vocabularies of similar strings (paths, URLs, and the
+// like) at the cost of slower lookups and String calls.
+// LGEFrontCodedStorage sorts the strings into small blocks and stores each
+// string after the first in a block as the length of the prefix it shares
+// with its predecessor plus the remaining suffix.  It saves less memory than
+// LGEFSTStorage when strings share suffixes but decodes strings faster.
+// Calling FreezeLGEsWith on a frozen table converts it to the requested
+// storage.
+func FreezeLGEsWith(storage LGEStorage) error {
+	lge.Lock()
+	defer lge.Unlock()
+	err := lge.flushPending()
+	if err != nil {
+		return err
+	}
+	lge.freeze(storage)
+	return nil
+}
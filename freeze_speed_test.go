@@ -0,0 +1,140 @@
+// This file measures the performance of lookups in frozen symbol tables.
+
+package intern_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// benchmarkEqLookup measures the throughput of parallel lookups of existing
+// Eqs in either a live or a frozen Eq table.
+func benchmarkEqLookup(b *testing.B, freeze bool) {
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	const ns = 10000 // Number of strings to intern
+	strs := generateRandomStrings(ns)
+	intern.NewEqMulti(strs)
+	if freeze {
+		intern.FreezeEqs()
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			if intern.NewEq(strs[i%ns]).String() != "" {
+				i++
+			}
+		}
+	})
+}
+
+// BenchmarkLiveEqLookup measures the throughput of parallel lookups in a
+// live Eq table.
+func BenchmarkLiveEqLookup(b *testing.B) {
+	benchmarkEqLookup(b, false)
+}
+
+// BenchmarkFrozenEqLookup measures the throughput of parallel lookups in a
+// frozen Eq table.
+func BenchmarkFrozenEqLookup(b *testing.B) {
+	benchmarkEqLookup(b, true)
+}
+
+// benchmarkLGELookup measures the throughput of parallel lookups of existing
+// LGEs in either a live or a frozen LGE table.
+func benchmarkLGELookup(b *testing.B, freeze bool, storage intern.LGEStorage) {
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	const ns = 10000 // Number of strings to intern
+	strs := generateRandomStrings(ns)
+	_, err := intern.NewLGEMulti(strs)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if freeze {
+		err = intern.FreezeLGEsWith(storage)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			l, _ := intern.LookupLGE(strs[i%ns])
+			if l.String() != "" {
+				i++
+			}
+		}
+	})
+}
+
+// BenchmarkLiveLGELookup measures the throughput of parallel lookups in a
+// live LGE table.
+func BenchmarkLiveLGELookup(b *testing.B) {
+	benchmarkLGELookup(b, false, intern.LGEHashStorage)
+}
+
+// BenchmarkFrozenLGELookup measures the throughput of parallel lookups in a
+// frozen LGE table.
+func BenchmarkFrozenLGELookup(b *testing.B) {
+	benchmarkLGELookup(b, true, intern.LGEHashStorage)
+}
+
+// BenchmarkFSTLGELookup measures the throughput of parallel lookups in a
+// frozen LGE table stored as a finite-state transducer.
+func BenchmarkFSTLGELookup(b *testing.B) {
+	benchmarkLGELookup(b, true, intern.LGEFSTStorage)
+}
+
+// benchmarkLGEStorage measures the memory consumed by a frozen table of
+// similar LGEs stored in a given way and the time needed to convert LGEs back
+// to strings.
+func benchmarkLGEStorage(b *testing.B, storage intern.LGEStorage) {
+	// Intern a set of strings that nothing else references then freeze
+	// the table and measure the memory it retains.
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	const ns = 100000 // Number of strings to intern
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	syms, err := intern.NewLGEMulti(generateSimilarStrings(ns))
+	if err != nil {
+		b.Fatal(err)
+	}
+	err = intern.FreezeLGEsWith(storage)
+	if err != nil {
+		b.Fatal(err)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	// Measure the time needed to convert LGEs to strings.
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Dummy += uint64(len(syms[(i*7919)%ns].String()))
+	}
+	b.ReportMetric((float64(after.HeapAlloc)-float64(before.HeapAlloc))/ns, "bytes/string")
+}
+
+// BenchmarkHashLGEStorage measures the memory and String performance of a
+// frozen table of similar LGEs stored with a minimal perfect hash.
+func BenchmarkHashLGEStorage(b *testing.B) {
+	benchmarkLGEStorage(b, intern.LGEHashStorage)
+}
+
+// BenchmarkFSTLGEStorage measures the memory and String performance of a
+// frozen table of similar LGEs stored as a finite-state transducer.
+func BenchmarkFSTLGEStorage(b *testing.B) {
+	benchmarkLGEStorage(b, intern.LGEFSTStorage)
+}
+
+// BenchmarkFrontCodedLGEStorage measures the memory and String performance
+// of a frozen table of similar LGEs stored with front coding.
+func BenchmarkFrontCodedLGEStorage(b *testing.B) {
+	benchmarkLGEStorage(b, intern.LGEFrontCodedStorage)
+}
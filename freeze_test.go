@@ -0,0 +1,174 @@
+// This file provides unit tests for frozen symbol tables.
+
+package intern_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// expectFrozen fails a test if an error is not an ErrFrozen PkgError.
+func expectFrozen(t *testing.T, err error) {
+	t.Helper()
+	if e, ok := err.(*intern.PkgError); !ok || e.Code != intern.ErrFrozen {
+		t.Fatalf("Expected ErrFrozen but saw %v", err)
+	}
+}
+
+// TestFreezeEqs ensures that a frozen Eq table supports lookups but not
+// insertions.
+func TestFreezeEqs(t *testing.T) {
+	// Create and freeze a table of Eqs.
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	syms := intern.NewEqMulti(ozChars)
+	intern.FreezeEqs()
+	intern.FreezeEqs() // Freezing twice should be harmless.
+
+	// Ensure that existing Eqs can still be looked up.
+	for i, s := range ozChars {
+		if e := intern.NewEq(s); e != syms[i] {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, syms[i], e)
+		}
+		if e, ok := intern.LookupEq(s); !ok || e != syms[i] {
+			t.Fatalf("Failed to look up %q", s)
+		}
+		if syms[i].String() != s {
+			t.Fatalf("Expected %q but saw %q", s, syms[i])
+		}
+	}
+	if syms2 := intern.NewEqMulti(ozChars[:3]); syms2[2] != syms[2] {
+		t.Fatalf("Expected %d but saw %d", syms[2], syms2[2])
+	}
+
+	// Ensure that new strings are rejected.
+	if _, ok := intern.LookupEq("Alice"); ok {
+		t.Fatal("Unexpectedly found Alice in a frozen table")
+	}
+	var e intern.Eq
+	expectFrozen(t, e.UnmarshalText([]byte("Alice")))
+	expectFrozen(t, intern.ReserveEqs(map[string]intern.Eq{"Alice": 1000}))
+	expectFrozen(t, intern.ReserveEqRange(1000, 2000))
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			expectFrozen(t, err)
+		}()
+		_ = intern.NewEq("Alice")
+		t.Fatal("NewEq failed to panic on a frozen table")
+	}()
+	_, err := intern.DefaultEqTable().Intern([]string{ozChars[0], "Alice"})
+	expectFrozen(t, err)
+	src := intern.NewEqTable()
+	src.NewEq("Alice")
+	_, err = intern.MergeEqTables(intern.DefaultEqTable(), src)
+	expectFrozen(t, err)
+
+	// Ensure that forgetting all Eqs thaws the table.
+	intern.ForgetAllEqs()
+	if intern.NewEq("Alice").String() != "Alice" {
+		t.Fatal("Failed to thaw the Eq table")
+	}
+}
+
+// TestFreezeLGEs ensures that a frozen LGE table supports lookups but not
+// insertions.
+func TestFreezeLGEs(t *testing.T) {
+	// Create and freeze a table of LGEs, leaving some strings pending.
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	syms, err := intern.NewLGEMulti(ozChars[:50])
+	if err != nil {
+		t.Fatal(err)
+	}
+	intern.PreLGEMulti(ozChars[50:])
+	err = intern.FreezeLGEs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure that existing and pending LGEs can be looked up.
+	for i, s := range ozChars {
+		l, err := intern.NewLGE(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < len(syms) && l != syms[i] {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, syms[i], l)
+		}
+		if l2, ok := intern.LookupLGE(s); !ok || l2 != l {
+			t.Fatalf("Failed to look up %q", s)
+		}
+		if l.String() != s {
+			t.Fatalf("Expected %q but saw %q", s, l)
+		}
+	}
+	if _, err = intern.NewLGEMulti(ozChars); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure that new strings are rejected.
+	_, err = intern.NewLGE("Alice")
+	expectFrozen(t, err)
+	_, err = intern.NewLGEMulti([]string{ozChars[0], "Alice"})
+	expectFrozen(t, err)
+	_, err = intern.RemapAllLGEs()
+	expectFrozen(t, err)
+	var l intern.LGE
+	expectFrozen(t, l.UnmarshalText([]byte("Alice")))
+	if _, ok := intern.LookupLGE("Alice"); ok {
+		t.Fatal("Unexpectedly found Alice in a frozen table")
+	}
+
+	// Ensure that forgetting all LGEs thaws the table.
+	intern.ForgetAllLGEs()
+	if _, err = intern.NewLGE("Alice"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFreezeConcurrent freezes the Eq table while other goroutines are
+// reading it in an attempt to expose race conditions.
+func TestFreezeConcurrent(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	intern.NewEqMulti(ozChars)
+	var wg sync.WaitGroup
+	for j := 0; j < runtime.NumCPU(); j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				s := ozChars[i%len(ozChars)]
+				if intern.NewEq(s).String() != s {
+					panic("Eq mismatch")
+				}
+			}
+		}()
+	}
+	intern.FreezeEqs()
+	wg.Wait()
+}
+
+// TestFreezeManyEqs ensures that a large frozen Eq table finds every string
+// it contains and no string it doesn't.
+func TestFreezeManyEqs(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	strs := generateRandomStrings(100000)
+	syms := intern.NewEqMulti(strs[:50000])
+	intern.FreezeEqs()
+	for i, s := range strs[:50000] {
+		if e, ok := intern.LookupEq(s); !ok || e != syms[i] {
+			t.Fatalf("Failed to look up %q", s)
+		}
+	}
+	for _, s := range strs[50000:] {
+		if e, ok := intern.LookupEq(s); ok && e.String() != s {
+			t.Fatalf("Looked up %q but found %q", s, e)
+		}
+	}
+}
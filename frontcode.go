@@ -0,0 +1,205 @@
+// This file provides front-coded string storage for use by frozen LGE
+// tables.
+
+package intern
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+)
+
+// frontBlock is the number of strings in each block of front-coded storage.
+// Larger blocks save more memory but take longer to decode.
+const frontBlock = 16
+
+// A frontStore is a frozenStore that keeps its strings sorted and front
+// coded: Strings are grouped into blocks, the first string in each block is
+// stored in full, and each subsequent string is stored as the length of the
+// prefix it shares with its predecessor plus the remaining suffix.  It is
+// suitable only for LGEs, whose order matches their strings' order.
+type frontStore struct {
+	data  string   // Encoded blocks, concatenated
+	start []uint32 // Offset into data of each block
+	syms  []symbol // Symbol of each string, by rank
+}
+
+// newFrontStore constructs a frontStore from a sorted list of strings and
+// their symbols.
+func newFrontStore(strs []string, syms []symbol) *frontStore {
+	var sb strings.Builder
+	var buf []byte
+	fs := &frontStore{
+		start: make([]uint32, 0, (len(strs)+frontBlock-1)/frontBlock),
+		syms:  syms,
+	}
+	for i, s := range strs {
+		buf = buf[:0]
+		if i%frontBlock == 0 {
+			// Store the first string in each block in full.
+			fs.start = append(fs.start, uint32(sb.Len()))
+			buf = binary.AppendUvarint(buf, uint64(len(s)))
+			sb.Write(buf)
+			sb.WriteString(s)
+			continue
+		}
+
+		// Store subsequent strings as a shared-prefix length and a
+		// suffix.
+		prev := strs[i-1]
+		p := 0
+		for p < len(s) && p < len(prev) && s[p] == prev[p] {
+			p++
+		}
+		buf = binary.AppendUvarint(buf, uint64(p))
+		buf = binary.AppendUvarint(buf, uint64(len(s)-p))
+		sb.Write(buf)
+		sb.WriteString(s[p:])
+	}
+	fs.data = sb.String()
+	return fs
+}
+
+// uvarint decodes an unsigned varint at a given offset into a frontStore's
+// data.  It returns the value and the offset just past it.
+func (fs *frontStore) uvarint(off int) (int, int) {
+	var v uint64
+	for shift := 0; ; shift += 7 {
+		c := fs.data[off]
+		off++
+		v |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			return int(v), off
+		}
+	}
+}
+
+// head returns the first string in a block.
+func (fs *frontStore) head(b int) string {
+	n, off := fs.uvarint(int(fs.start[b]))
+	return fs.data[off : off+n]
+}
+
+// scan decodes the strings in a block in order, passing each string's rank
+// and contents to a function until the function returns false.  The contents
+// are valid only for the duration of the call.
+func (fs *frontStore) scan(b int, f func(r int, s []byte) bool) {
+	n, off := fs.uvarint(int(fs.start[b]))
+	s := []byte(fs.data[off : off+n])
+	off += n
+	r := b * frontBlock
+	for {
+		if !f(r, s) {
+			return
+		}
+		r++
+		if r%frontBlock == 0 || r == len(fs.syms) {
+			return
+		}
+		var p int
+		p, off = fs.uvarint(off)
+		n, off = fs.uvarint(off)
+		s = append(s[:p], fs.data[off:off+n]...)
+		off += n
+	}
+}
+
+// strAt returns the string with a given rank.
+func (fs *frontStore) strAt(r int) string {
+	var str string
+	fs.scan(r/frontBlock, func(r2 int, s []byte) bool {
+		if r2 < r {
+			return true
+		}
+		str = string(s)
+		return false
+	})
+	return str
+}
+
+// lowerBound returns the number of strings in a frontStore that are less
+// than a given string.
+func (fs *frontStore) lowerBound(s string) int {
+	// Find the block that would contain s.
+	b := sort.Search(len(fs.start), func(i int) bool { return fs.head(i) >= s })
+	if b == 0 {
+		return 0
+	}
+
+	// Find the position of s within the block.
+	r := b * frontBlock
+	if r > len(fs.syms) {
+		r = len(fs.syms) // The last block may be partial.
+	}
+	fs.scan(b-1, func(r2 int, s2 []byte) bool {
+		if string(s2) >= s {
+			r = r2
+			return false
+		}
+		return true
+	})
+	return r
+}
+
+// lookup returns the symbol associated with a string in a frontStore.
+func (fs *frontStore) lookup(s string) (symbol, bool) {
+	r := fs.lowerBound(s)
+	if r == len(fs.syms) || fs.strAt(r) != s {
+		return 0, false
+	}
+	return fs.syms[r], true
+}
+
+// str returns the string associated with a symbol in a frontStore.
+func (fs *frontStore) str(sym symbol) (string, bool) {
+	r := sort.Search(len(fs.syms), func(i int) bool { return fs.syms[i] >= sym })
+	if r == len(fs.syms) || fs.syms[r] != sym {
+		return "", false
+	}
+	return fs.strAt(r), true
+}
+
+// size returns the number of strings in a frontStore.
+func (fs *frontStore) size() int {
+	return len(fs.syms)
+}
+
+// symAt returns the symbol of the string with a given rank in a frontStore.
+func (fs *frontStore) symAt(r int) symbol {
+	return fs.syms[r]
+}
+
+// prefixRanks returns the half-open range of ranks of the strings in a
+// frontStore that begin with a given prefix.
+func (fs *frontStore) prefixRanks(prefix string) (lo, hi int) {
+	lo = fs.lowerBound(prefix)
+
+	// All strings beginning with the prefix precede the prefix with its
+	// last non-0xFF byte incremented and all subsequent bytes removed.
+	end := []byte(prefix)
+	for len(end) > 0 && end[len(end)-1] == 0xff {
+		end = end[:len(end)-1]
+	}
+	if len(end) == 0 {
+		return lo, len(fs.syms)
+	}
+	end[len(end)-1]++
+	return lo, fs.lowerBound(string(end))
+}
+
+// index constructs a minimal perfect hash of a frontStore's mappings.
+func (fs *frontStore) index() *mph {
+	strToSym := make(map[string]symbol, len(fs.syms))
+	for b := range fs.start {
+		fs.scan(b, func(r int, s []byte) bool {
+			strToSym[string(s)] = fs.syms[r]
+			return true
+		})
+	}
+	return newMPH(strToSym)
+}
+
+// storage reports that a frontStore uses LGEFrontCodedStorage.
+func (fs *frontStore) storage() LGEStorage {
+	return LGEFrontCodedStorage
+}
@@ -0,0 +1,66 @@
+// This file provides unit tests for frozen LGE tables stored with front
+// coding.
+
+package intern_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// TestFreezeLGEsFrontCoded ensures that a front-coded LGE table supports
+// lookups but not insertions.
+func TestFreezeLGEsFrontCoded(t *testing.T) {
+	// Create and freeze a table of LGEs.  The number of strings is not a
+	// multiple of the block size.
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	strs := append(generateSimilarStrings(1000), ozChars...)
+	strs = append(strs, "", "Dorothy Gale's dog", "D", "\xff\xff")
+	syms, err := intern.NewLGEMulti(strs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = intern.FreezeLGEsWith(intern.LGEFrontCodedStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLGEs(t, strs, syms)
+
+	// Ensure that strings not in the table, including prefixes and
+	// extensions of strings in the table, are not found.
+	for _, s := range []string{"Alice", "Doroth", "Dorothy Galez", "String", "Dorothy Gale's dog!", "\xff", "\xff\xff\xff"} {
+		if _, ok := intern.LookupLGE(s); ok {
+			t.Fatalf("Unexpectedly found %q in a frozen table", s)
+		}
+	}
+	_, err = intern.NewLGE("Alice")
+	expectFrozen(t, err)
+	if lo, hi, ok := intern.LGEPrefixRange("\xff"); !ok || lo != hi || lo.String() != "\xff\xff" {
+		t.Fatalf("Expected prefix \"\\xff\" to match only \"\\xff\\xff\" but saw %q to %q", lo, hi)
+	}
+
+	// Ensure that converting the table to other storage preserves all
+	// LGEs.
+	if err = intern.FreezeLGEsWith(intern.LGEFSTStorage); err != nil {
+		t.Fatal(err)
+	}
+	checkLGEs(t, strs, syms)
+	if err = intern.FreezeLGEsWith(intern.LGEFrontCodedStorage); err != nil {
+		t.Fatal(err)
+	}
+	checkLGEs(t, strs, syms)
+
+	// Ensure that a snapshot of the table can be read back.
+	var buf bytes.Buffer
+	if err = intern.WriteLGESnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	intern.ForgetAllLGEs()
+	if err = intern.ReadLGESnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkLGEs(t, strs, syms)
+}
@@ -0,0 +1,263 @@
+// This file provides a finite-state transducer for use by frozen LGE tables.
+
+package intern
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// An fst is a minimal acyclic finite-state transducer that maps each of a
+// sorted set of strings to its rank within the set.  States with identical
+// futures are merged, so strings that share prefixes or suffixes share
+// storage.  Each arc is labeled with an input byte and an output, which is
+// the number of strings that taking the arc skips over.  A string's rank is
+// the sum of the outputs along its path.  Because each state also records the
+// number of strings it accepts, the transducer can be run backwards to map a
+// rank to its string.
+type fst struct {
+	root   uint32   // Initial state
+	final  []bool   // Whether each state accepts
+	count  []uint32 // Number of strings accepted from each state
+	first  []uint32 // Index of each state's first arc, plus a sentinel
+	label  []byte   // Input byte of each arc
+	target []uint32 // Destination state of each arc
+	out    []uint32 // Output of each arc
+}
+
+// arc returns the arc leaving a given state on a given byte.
+func (f *fst) arc(q uint32, c byte) (uint32, bool) {
+	lo, hi := f.first[q], f.first[q+1]
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		switch {
+		case f.label[mid] < c:
+			lo = mid + 1
+		case f.label[mid] > c:
+			hi = mid
+		default:
+			return mid, true
+		}
+	}
+	return 0, false
+}
+
+// walk follows a string from the initial state.  It returns the state reached
+// and the number of strings that precede every string beginning with s.  It
+// returns false if no string begins with s.
+func (f *fst) walk(s string) (uint32, uint32, bool) {
+	q, r := f.root, uint32(0)
+	for i := 0; i < len(s); i++ {
+		a, ok := f.arc(q, s[i])
+		if !ok {
+			return 0, 0, false
+		}
+		r += f.out[a]
+		q = f.target[a]
+	}
+	return q, r, true
+}
+
+// rank returns the rank of a string.
+func (f *fst) rank(s string) (int, bool) {
+	q, r, ok := f.walk(s)
+	if !ok || !f.final[q] {
+		return 0, false
+	}
+	return int(r), true
+}
+
+// prefixRanks returns the half-open range of ranks of the strings beginning
+// with a given prefix.
+func (f *fst) prefixRanks(prefix string) (lo, hi int) {
+	q, r, ok := f.walk(prefix)
+	if !ok {
+		return 0, 0
+	}
+	return int(r), int(r + f.count[q])
+}
+
+// str returns the string with a given rank.
+func (f *fst) str(r int) string {
+	var b []byte
+	q, rr := f.root, uint32(r)
+	for !f.final[q] || rr > 0 {
+		// Find the last arc whose output does not exceed the rank.
+		lo, hi := f.first[q], f.first[q+1]
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if f.out[mid] <= rr {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		b = append(b, f.label[lo])
+		rr -= f.out[lo]
+		q = f.target[lo]
+	}
+	return string(b)
+}
+
+// An fstNode is a state on the path of the most recently added string.
+// Unlike the states in an fst, it can still be modified.
+type fstNode struct {
+	final   bool     // Whether the state accepts
+	labels  []byte   // Input byte of each arc
+	targets []uint32 // Destination state of each arc
+}
+
+// An fstBuilder constructs an fst from a sorted list of strings using the
+// incremental algorithm of Daciuk et al.
+type fstBuilder struct {
+	f        *fst              // Transducer under construction
+	register map[string]uint32 // Map from a state's signature to the state
+	sig      []byte            // Scratch space for signatures
+}
+
+// add adds a state to the transducer unless an equivalent state already
+// exists.  It returns the state's number.
+func (b *fstBuilder) add(n *fstNode) uint32 {
+	// Two states are equivalent if they agree on finality and on all
+	// outgoing arcs.
+	b.sig = b.sig[:0]
+	if n.final {
+		b.sig = append(b.sig, 1)
+	} else {
+		b.sig = append(b.sig, 0)
+	}
+	for i, c := range n.labels {
+		b.sig = append(b.sig, c)
+		b.sig = binary.AppendUvarint(b.sig, uint64(n.targets[i]))
+	}
+	if q, ok := b.register[string(b.sig)]; ok {
+		return q
+	}
+
+	// Append a new state.  Each arc's output is the number of strings
+	// accepted by the state itself and by its preceding arcs.
+	f := b.f
+	q := uint32(len(f.final))
+	b.register[string(b.sig)] = q
+	var n0 uint32
+	if n.final {
+		n0 = 1
+	}
+	f.final = append(f.final, n.final)
+	f.first = append(f.first, uint32(len(f.label)))
+	for i, c := range n.labels {
+		f.label = append(f.label, c)
+		f.target = append(f.target, n.targets[i])
+		f.out = append(f.out, n0)
+		n0 += f.count[n.targets[i]]
+	}
+	f.count = append(f.count, n0)
+	return q
+}
+
+// reduce adds to the transducer all states on the path beyond a given depth,
+// deepest first, and truncates the path to that depth.
+func (b *fstBuilder) reduce(path []*fstNode, depth int) []*fstNode {
+	for i := len(path) - 1; i > depth; i-- {
+		parent := path[i-1]
+		parent.targets[len(parent.targets)-1] = b.add(path[i])
+	}
+	return path[:depth+1]
+}
+
+// newFST constructs a minimal transducer that maps each of a sorted list of
+// distinct strings to its index in the list.
+func newFST(strs []string) *fst {
+	b := &fstBuilder{
+		f:        &fst{},
+		register: make(map[string]uint32),
+	}
+	path := []*fstNode{{}}
+	prev := ""
+	for _, s := range strs {
+		// Determine how much of the path the new string shares with
+		// its predecessor.
+		p := 0
+		for p < len(s) && p < len(prev) && s[p] == prev[p] {
+			p++
+		}
+		path = b.reduce(path, p)
+
+		// Extend the path with the rest of the new string.
+		for i := p; i < len(s); i++ {
+			path[i].labels = append(path[i].labels, s[i])
+			path[i].targets = append(path[i].targets, 0)
+			path = append(path, &fstNode{})
+		}
+		path[len(s)].final = true
+		prev = s
+	}
+	path = b.reduce(path, 0)
+	b.f.root = b.add(path[0])
+	b.f.first = append(b.f.first, uint32(len(b.f.label)))
+	return b.f
+}
+
+// An fstStore is a frozenStore that keeps its strings in an fst.  It is
+// suitable only for LGEs, whose order matches their strings' order.
+type fstStore struct {
+	fst  *fst     // Map from strings to ranks
+	syms []symbol // Symbol of each string, by rank
+}
+
+// newFSTStore constructs an fstStore from a sorted list of strings and their
+// symbols.
+func newFSTStore(strs []string, syms []symbol) *fstStore {
+	return &fstStore{
+		fst:  newFST(strs),
+		syms: syms,
+	}
+}
+
+// lookup returns the symbol associated with a string in an fstStore.
+func (fs *fstStore) lookup(s string) (symbol, bool) {
+	r, ok := fs.fst.rank(s)
+	if !ok {
+		return 0, false
+	}
+	return fs.syms[r], true
+}
+
+// str returns the string associated with a symbol in an fstStore.
+func (fs *fstStore) str(sym symbol) (string, bool) {
+	r := sort.Search(len(fs.syms), func(i int) bool { return fs.syms[i] >= sym })
+	if r == len(fs.syms) || fs.syms[r] != sym {
+		return "", false
+	}
+	return fs.fst.str(r), true
+}
+
+// size returns the number of strings in an fstStore.
+func (fs *fstStore) size() int {
+	return len(fs.syms)
+}
+
+// symAt returns the symbol of the string with a given rank in an fstStore.
+func (fs *fstStore) symAt(r int) symbol {
+	return fs.syms[r]
+}
+
+// prefixRanks returns the half-open range of ranks of the strings in an
+// fstStore that begin with a given prefix.
+func (fs *fstStore) prefixRanks(prefix string) (lo, hi int) {
+	return fs.fst.prefixRanks(prefix)
+}
+
+// index constructs a minimal perfect hash of an fstStore's mappings.
+func (fs *fstStore) index() *mph {
+	strToSym := make(map[string]symbol, len(fs.syms))
+	for r, sym := range fs.syms {
+		strToSym[fs.fst.str(r)] = sym
+	}
+	return newMPH(strToSym)
+}
+
+// storage reports that an fstStore uses LGEFSTStorage.
+func (fs *fstStore) storage() LGEStorage {
+	return LGEFSTStorage
+}
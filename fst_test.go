@@ -0,0 +1,150 @@
+// This file provides unit tests for frozen LGE tables stored as finite-state
+// transducers and for ordered queries of LGEs.
+
+package intern_test
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// checkLGEs ensures that every string in a list maps to the given LGE and
+// back.
+func checkLGEs(t *testing.T, strs []string, syms []intern.LGE) {
+	t.Helper()
+	for i, s := range strs {
+		if l, ok := intern.LookupLGE(s); !ok || l != syms[i] {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, syms[i], l)
+		}
+		if syms[i].String() != s {
+			t.Fatalf("Expected %q but saw %q", s, syms[i])
+		}
+	}
+}
+
+// TestFreezeLGEsFST ensures that an LGE table stored as a finite-state
+// transducer supports lookups but not insertions.
+func TestFreezeLGEsFST(t *testing.T) {
+	// Create and freeze a table of LGEs.
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	strs := append(generateSimilarStrings(1000), ozChars...)
+	strs = append(strs, "", "Dorothy Gale's dog", "D")
+	syms, err := intern.NewLGEMulti(strs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = intern.FreezeLGEsWith(intern.LGEFSTStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLGEs(t, strs, syms)
+
+	// Ensure that strings not in the table, including prefixes and
+	// extensions of strings in the table, are not found.
+	for _, s := range []string{"Alice", "Doroth", "Dorothy Galez", "String", "Dorothy Gale's dog!"} {
+		if _, ok := intern.LookupLGE(s); ok {
+			t.Fatalf("Unexpectedly found %q in a frozen table", s)
+		}
+	}
+	_, err = intern.NewLGE("Alice")
+	expectFrozen(t, err)
+
+	// Ensure that converting the table to and from hash storage
+	// preserves all LGEs.
+	if err = intern.FreezeLGEs(); err != nil {
+		t.Fatal(err)
+	}
+	checkLGEs(t, strs, syms)
+	if err = intern.FreezeLGEsWith(intern.LGEFSTStorage); err != nil {
+		t.Fatal(err)
+	}
+	checkLGEs(t, strs, syms)
+
+	// Ensure that a snapshot of the table can be read back.
+	var buf bytes.Buffer
+	if err = intern.WriteLGESnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	intern.ForgetAllLGEs()
+	if err = intern.ReadLGESnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkLGEs(t, strs, syms)
+}
+
+// TestLGEPrefixRange compares LGEPrefixRange and EachLGE to a brute-force
+// search for live tables and for every form of frozen table.
+func TestLGEPrefixRange(t *testing.T) {
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	strs := append(generateSimilarStrings(100), ozChars...)
+	if _, err := intern.NewLGEMulti(strs); err != nil {
+		t.Fatal(err)
+	}
+	sorted := append([]string(nil), strs...)
+	sort.Strings(sorted)
+	prefixes := []string{"", "D", "Do", "Dorothy", "Dorothy Gale", "Dorothy Galez", "Z", "~", "String comparisons", "String comparisons can be slow when the strings to compare have a long prefix in common.  My favorite number is now 00000000000005"}
+	for _, storage := range []string{"live", "hash", "FST", "front-coded"} {
+		switch storage {
+		case "hash":
+			if err := intern.FreezeLGEs(); err != nil {
+				t.Fatal(err)
+			}
+		case "FST":
+			if err := intern.FreezeLGEsWith(intern.LGEFSTStorage); err != nil {
+				t.Fatal(err)
+			}
+		case "front-coded":
+			if err := intern.FreezeLGEsWith(intern.LGEFrontCodedStorage); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, p := range prefixes {
+			// Find all matching strings the slow way.
+			var want []string
+			for _, s := range sorted {
+				if strings.HasPrefix(s, p) {
+					want = append(want, s)
+				}
+			}
+
+			// Compare EachLGE's output.
+			var got []string
+			intern.EachLGE(p, func(l intern.LGE) bool {
+				got = append(got, l.String())
+				return true
+			})
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Fatalf("%s: expected prefix %q to visit %q but saw %q", storage, p, want, got)
+			}
+
+			// Compare LGEPrefixRange's output.
+			lo, hi, ok := intern.LGEPrefixRange(p)
+			switch {
+			case len(want) == 0 && ok:
+				t.Fatalf("%s: unexpectedly found prefix %q", storage, p)
+			case len(want) == 0:
+			case !ok:
+				t.Fatalf("%s: failed to find prefix %q", storage, p)
+			case lo.String() != want[0] || hi.String() != want[len(want)-1]:
+				t.Fatalf("%s: expected prefix %q to span %q to %q but saw %q to %q",
+					storage, p, want[0], want[len(want)-1], lo, hi)
+			}
+		}
+	}
+
+	// Ensure that EachLGE stops when asked to.
+	n := 0
+	intern.EachLGE("", func(l intern.LGE) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Fatalf("Expected EachLGE to stop after 3 LGEs but saw %d", n)
+	}
+}
@@ -0,0 +1,109 @@
+// This file provides Eqs derived from hashes of their strings.
+
+package intern
+
+import "fmt"
+
+// eqHashSeed is the seed for the hash from which EqHashMode derives Eqs.
+// Changing it changes every hashed Eq, so it must never change.
+const eqHashSeed = 0x696e7465726e4571 // "internEq"
+
+// maxEqProbes is the number of Eqs in each string's probe sequence.
+const maxEqProbes = 64
+
+// probeSym returns the Eq symbol at a given position in a string's probe
+// sequence.  The high bit is always clear so hashed Eqs can never be mistaken
+// for inline Eqs.
+func probeSym(s string, probe int) symbol {
+	h := hashString(s, eqHashSeed)
+	return symbol(mix(h+uint64(probe)*0x9e3779b97f4a7c15)) &^ inlineTag
+}
+
+// assignHashedEq assigns a string the first Eq in its probe sequence that is
+// neither zero, reserved, nor assigned to another string, and records the
+// number of probes that were needed if not zero.  The caller must hold the
+// state's write lock and must already have checked that the string is new.
+func (st *state) assignHashedEq(s string) (symbol, error) {
+	for p := 0; p < maxEqProbes; p++ {
+		sym := probeSym(s, p)
+		if sym == 0 {
+			continue
+		}
+		if _, ok := st.inReservedRange(sym); ok {
+			continue
+		}
+		if _, taken := st.symToStr[sym]; taken {
+			continue
+		}
+		if p > 0 {
+			if st.probes == nil {
+				st.probes = make(map[symbol]int)
+			}
+			st.probes[sym] = p
+		}
+		st.setSym(s, sym)
+		return sym, nil
+	}
+	e := &PkgError{
+		Code: ErrTableFull,
+		Str:  s,
+		msg:  fmt.Sprintf("Unable to assign %q an Eq; all %d probes collided", s, maxEqProbes),
+	}
+	return 0, e
+}
+
+// recordProbes records the number of probes needed to assign each of a
+// frozen state's Eqs, as assignHashedEq would have.  The caller must hold the
+// state's write lock.
+func (st *state) recordProbes() {
+	ft := st.frozenTable()
+	for r := 0; r < ft.size(); r++ {
+		sym := ft.symAt(r)
+		s, _ := ft.str(sym)
+		for p := 1; p < maxEqProbes; p++ {
+			if probeSym(s, p) == sym {
+				if st.probes == nil {
+					st.probes = make(map[symbol]int)
+				}
+				st.probes[sym] = p
+				break
+			}
+		}
+	}
+}
+
+// HashEq returns the Eq at a given position in a string's probe sequence.
+// In EqHashMode (see SetEqMode), NewEq assigns each new string the first Eq
+// in its probe sequence that does not collide with an existing Eq.  Because
+// the sequence depends only on the string, cooperating processes agree on
+// the Eq of every string that did not collide in any of them, which, with
+// 63-bit hashes, is nearly every string.  A process that receives a string
+// and an Eq from another process can verify that the two agree by checking
+// that HashEq(s, 0) equals the Eq.  HashEq does not intern the string.
+func HashEq(s string, probe int) Eq {
+	return Eq(probeSym(s, probe))
+}
+
+// EqProbe returns the position of an Eq in its string's probe sequence, as
+// recorded when the Eq was assigned.  A position of zero means that the Eq is
+// the one that every cooperating process computes for the string; a
+// positive position means that the string's earlier Eqs collided with other
+// strings' Eqs.  The second return value is false if the Eq is unknown or was
+// not derived from a hash (for example, because it was reserved with
+// ReserveEqs or assigned outside EqHashMode).
+func EqProbe(e Eq) (int, bool) {
+	eq.RLock()
+	defer eq.RUnlock()
+	var s string
+	var ok bool
+	if ft := eq.frozenTable(); ft != nil {
+		s, ok = ft.str(symbol(e))
+	} else {
+		s, ok = eq.symToStr[symbol(e)]
+	}
+	if !ok {
+		return 0, false
+	}
+	p := eq.probes[symbol(e)]
+	return p, probeSym(s, p) == symbol(e)
+}
@@ -0,0 +1,97 @@
+// This file provides unit tests for Eqs derived from string hashes.
+
+package intern_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// TestEqHashMode ensures that hashed Eqs do not depend on the order in which
+// strings are interned.
+func TestEqHashMode(t *testing.T) {
+	useEqMode(t, intern.EqHashMode)
+	defer useEqMode(t, intern.EqTableMode)
+	fwd := intern.NewEqMulti(ozChars)
+	intern.ForgetAllEqs()
+	for i := len(ozChars) - 1; i >= 0; i-- {
+		s := ozChars[i]
+		e := intern.NewEq(s)
+		if e != fwd[i] {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, fwd[i], e)
+		}
+		if e != intern.HashEq(s, 0) {
+			t.Fatalf("Expected %q to map to its hash, %d, but saw %d", s, intern.HashEq(s, 0), e)
+		}
+		if p, ok := intern.EqProbe(e); !ok || p != 0 {
+			t.Fatalf("Expected %q to need no probes but saw %d", s, p)
+		}
+		if e.String() != s {
+			t.Fatalf("Expected %q but saw %q", s, e)
+		}
+	}
+}
+
+// TestEqHashCollision forces a hash collision and ensures that it is
+// resolved and recorded, including across a snapshot.
+func TestEqHashCollision(t *testing.T) {
+	useEqMode(t, intern.EqHashMode)
+	defer useEqMode(t, intern.EqTableMode)
+	const s = "Toto"
+	err := intern.ReserveEqs(map[string]intern.Eq{"Dorothy Gale": intern.HashEq(s, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := intern.NewEq(s)
+	if e != intern.HashEq(s, 1) {
+		t.Fatalf("Expected %q to map to %d but saw %d", s, intern.HashEq(s, 1), e)
+	}
+	if p, ok := intern.EqProbe(e); !ok || p != 1 {
+		t.Fatalf("Expected %q to need 1 probe but saw %d", s, p)
+	}
+	if _, ok := intern.EqProbe(intern.HashEq(s, 0)); ok {
+		t.Fatal("Reserved Eq was reported as hashed")
+	}
+
+	// Probe counts survive a snapshot.
+	var buf bytes.Buffer
+	if err = intern.WriteEqSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err = intern.ReadEqSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := intern.EqProbe(e); !ok || p != 1 {
+		t.Fatalf("Expected %q to need 1 probe but saw %d", s, p)
+	}
+}
+
+// TestEqHashEq32 ensures that NewEq32 rejects hashed Eqs that do not fit in
+// 32 bits, which is nearly all of them.
+func TestEqHashEq32(t *testing.T) {
+	useEqMode(t, intern.EqHashMode)
+	defer useEqMode(t, intern.EqTableMode)
+	fail := 0
+	for _, s := range ozChars {
+		_, err := intern.NewEq32(s)
+		if intern.HashEq(s, 0) <= math.MaxUint32 {
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if pe, ok := err.(*intern.PkgError); !ok || pe.Code != intern.ErrOutOfRange {
+			t.Fatalf("Expected ErrOutOfRange for %q but saw %v", s, err)
+		}
+		if _, err = intern.NewEq(s).Eq32(); err == nil {
+			t.Fatalf("Converted the Eq of %q to an Eq32", s)
+		}
+		fail++
+	}
+	if fail < len(ozChars)-1 {
+		t.Fatalf("Expected nearly all %d strings to fail but saw %d failures", len(ozChars), fail)
+	}
+}
@@ -224,3 +224,26 @@ func ExampleForgetAllLGEs() {
 		fmt.Println(c)
 	}
 }
+
+// Sort a list of strings using an LGEHeap, which handles LGE exhaustion
+// automatically.
+func ExampleLGEHeap() {
+	var h intern.LGEHeap[int]
+	for i, c := range []string{"yellow", "white", "violet", "tan", "red", "plum", "navy"} {
+		if _, err := h.Push(c, i); err != nil {
+			panic(err)
+		}
+	}
+	for h.Len() > 0 {
+		it := h.Pop()
+		fmt.Println(it, it.Value)
+	}
+	// Output:
+	// navy 6
+	// plum 5
+	// red 4
+	// tan 3
+	// violet 2
+	// white 1
+	// yellow 0
+}
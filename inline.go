@@ -0,0 +1,106 @@
+// This file provides an Eq encoding that packs short strings directly into
+// their Eqs.
+
+package intern
+
+import "fmt"
+
+// An EqMode specifies how NewEq maps strings to Eqs.
+type EqMode int
+
+// These constants represent the supported Eq modes.
+const (
+	EqTableMode  EqMode = iota // Record every string in the Eq table
+	EqInlineMode               // Pack short strings into their Eqs
+	EqHashMode                 // Derive Eqs from hashes of their strings
+)
+
+// inlineTag is the symbol bit that marks an Eq as containing its own string.
+const inlineTag = symbol(1) << 63
+
+// maxInline is the length in bytes of the longest string that can be packed
+// into an Eq.
+const maxInline = 7
+
+// inlineSym packs a string of at most maxInline bytes into a symbol.  Byte i
+// of the string occupies bits 8i through 8i+7, the string's length occupies
+// bits 56 through 58, and inlineTag is set.
+func inlineSym(s string) symbol {
+	sym := inlineTag | symbol(len(s))<<56
+	for i := 0; i < len(s); i++ {
+		sym |= symbol(s[i]) << (8 * i)
+	}
+	return sym
+}
+
+// inlineStr unpacks a string from a symbol produced by inlineSym.
+func inlineStr(sym symbol) string {
+	var buf [maxInline]byte
+	n := int(sym>>56) & 7
+	for i := 0; i < n; i++ {
+		buf[i] = byte(sym >> (8 * i))
+	}
+	return string(buf[:n])
+}
+
+// inlineEq returns the symbol into which a string is packed if inline mode is
+// enabled and the string is short enough to pack.
+func (st *state) inlineEq(s string) (symbol, bool) {
+	if len(s) <= maxInline && st.inline.Load() {
+		return inlineSym(s), true
+	}
+	return 0, false
+}
+
+// eqString converts an Eq symbol, inline or otherwise, back to a string.  It
+// panics if given an Eq that was not created using NewEq.
+func (st *state) eqString(sym symbol) string {
+	if sym&inlineTag != 0 && st.inline.Load() {
+		return inlineStr(sym)
+	}
+	return st.toString(sym, "Eq")
+}
+
+// SetEqMode selects how NewEq maps strings to Eqs.  In the default mode,
+// EqTableMode, every string is recorded in the Eq table.  In EqInlineMode,
+// strings of up to seven bytes are instead packed directly into their Eqs,
+// with the high bit set to distinguish them from table-assigned Eqs.  Such
+// strings never touch the table: NewEq, LookupEq, and String handle them
+// without locking, and they consume no memory beyond the Eq itself.  Equality
+// comparisons work as usual because each string still maps to exactly one
+// Eq.  However, queries over the set of interned strings, such as
+// ContainsEqs, FindEqs, NearestEqs, CompleteEqs, and LongestPrefixEq, see
+// only strings longer than seven bytes, and inline Eqs never fit in an Eq32.
+// In EqHashMode, each string's Eq is derived from a hash of its contents (see
+// HashEq), so independent processes agree on Eqs without exchanging a
+// dictionary.  Hashed Eqs are spread over 63 bits, so nearly all of them
+// exceed 2^32-1, and NewEq32 and Eq.Eq32 fail for almost every string.
+// Programs that need Eq32s should not use EqHashMode.
+//
+// SetEqMode returns an error if the mode is not one of the above or unless
+// the Eq table is empty, unfrozen, and free of reservations.  The mode
+// survives ForgetAllEqs but is not recorded in snapshots, so a program should
+// select the same mode before calling ReadEqSnapshot that was in effect when
+// it called WriteEqSnapshot.
+func SetEqMode(mode EqMode) error {
+	eq.Lock()
+	defer eq.Unlock()
+	if mode < EqTableMode || mode > EqHashMode {
+		return &PkgError{
+			Code: ErrOutOfRange,
+			msg:  fmt.Sprintf("Eq mode %d is not recognized", int(mode)),
+		}
+	}
+	if err := eq.checkFrozen("", "Eq"); err != nil {
+		return err
+	}
+	if len(eq.symToStr) > 0 || len(eq.ranges) > 0 {
+		return &PkgError{
+			Code: ErrNotEmpty,
+			msg:  "Unable to change the Eq mode; the Eq table is not empty",
+		}
+	}
+	eq.inline.Store(mode == EqInlineMode)
+	eq.hashed = mode == EqHashMode
+	return nil
+}
@@ -0,0 +1,82 @@
+// This file provides unit tests for packing short strings into Eqs.
+
+package intern_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// useEqMode empties the Eq table and selects a given Eq mode.
+func useEqMode(t testing.TB, mode intern.EqMode) {
+	intern.ForgetAllEqs()
+	if err := intern.SetEqMode(mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestEqInlineMode ensures that short strings are packed into Eqs and that
+// Eqs behave the same regardless of how they are represented.
+func TestEqInlineMode(t *testing.T) {
+	useEqMode(t, intern.EqInlineMode)
+	defer useEqMode(t, intern.EqTableMode)
+	strs := []string{"", "US", "de", "1234567", "12345678", "a\x00b", "Toto", "Dorothy Gale"}
+	eqs := intern.NewEqMulti(strs)
+	for i, s := range strs {
+		if e := intern.NewEq(s); e != eqs[i] {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, eqs[i], e)
+		}
+		if e, ok := intern.LookupEq(s); !ok || e != eqs[i] {
+			t.Fatalf("Expected to look up %q as %d but saw %d", s, eqs[i], e)
+		}
+		if eqs[i].String() != s {
+			t.Fatalf("Expected %q but saw %q", s, eqs[i])
+		}
+		for j := range strs[:i] {
+			if eqs[j] == eqs[i] {
+				t.Fatalf("%q and %q both map to %d", strs[j], s, eqs[i])
+			}
+		}
+	}
+
+	// Only long strings are recorded in the table.
+	if got := intern.ContainsEqs(""); len(got) != 2 || got[0] != eqs[4] || got[1] != eqs[7] {
+		t.Fatalf("Expected only long strings in the table but saw %v", got)
+	}
+
+	// The mode can't change while the table is in use.
+	var pe *intern.PkgError
+	if err := intern.SetEqMode(intern.EqTableMode); !errors.As(err, &pe) || pe.Code != intern.ErrNotEmpty {
+		t.Fatalf("Expected a nonempty-table error but saw %v", err)
+	}
+	if err := intern.SetEqMode(intern.EqMode(-1)); !errors.As(err, &pe) || pe.Code != intern.ErrOutOfRange {
+		t.Fatalf("Expected an out-of-range error but saw %v", err)
+	}
+	if err := intern.ReserveEqs(map[string]intern.Eq{"Oz": 100}); err == nil {
+		t.Fatal("Unexpectedly reserved an inline string")
+	}
+
+	// Short strings can still be interned after freezing.
+	intern.FreezeEqs()
+	if e := intern.NewEq("Ozma"); e.String() != "Ozma" {
+		t.Fatalf("Expected %q but saw %q", "Ozma", e)
+	}
+
+	// Eqs round-trip through JSON.
+	b, err := json.Marshal(eqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var eqs2 []intern.Eq
+	if err = json.Unmarshal(b, &eqs2); err != nil {
+		t.Fatal(err)
+	}
+	for i := range eqs {
+		if eqs2[i] != eqs[i] {
+			t.Fatalf("Expected %q to map to %d but saw %d", strs[i], eqs[i], eqs2[i])
+		}
+	}
+}
@@ -50,7 +50,44 @@ the RemapAllLGEs function can be called to completely redo the mapping from
 strings to LGE symbols.  The program will need to update any live LGE symbols
 it has stored in data structures.
 
-All functions in this package are thread-safe.
+SetLGEInlinePrefix makes such failures rarer by deriving each LGE's high-order
+bits from the first few bytes of its string.
+
+ReserveEqs and ReserveLGEs pin particular strings to particular symbols.
+The interngen command (in cmd/interngen) uses them to generate constants.
+ReserveEqRange sets aside ranges of Eqs, and LoadEqReservations reads
+reservations from a file.
+
+Eq32 is a 32-bit variant of Eq.
+
+SetEqMode(EqInlineMode) packs short strings directly into their Eqs.
+SetEqMode(EqHashMode) derives Eqs from hashes of their strings.
+
+NewEqTable and NewLGETable create independent symbol tables, and
+MergeEqTables and MergeLGETables combine them.
+
+DiffEqs, WriteEqDelta, and ApplyEqDelta replicate an Eq table to followers.
+The registry package (in the registry directory) shares one Eq table among
+the processes on a host.
+
+All functions in this package are thread-safe.  FreezeEqs and FreezeLGEs make
+the tables read-only so that lookups proceed without locking.
+WriteEqSnapshot, WriteLGESnapshot, ReadEqSnapshot, and ReadLGESnapshot save
+and restore frozen tables.
+FreezeLGEsWith can store a frozen LGE table compactly as a finite-state
+transducer or as front-coded blocks of sorted strings.
+LGEPrefixRange and EachLGE find LGEs by prefix.
+SetEqIndex replaces the map used to look up Eqs, for example with a DATrie.
+LongestPrefixEq and CompleteEqs answer prefix queries.
+ContainsEqs and FindEqs search for substrings and regular expressions.
+NearestEqs finds strings by edit distance.
+
+EqSet is a compressed bitmap set of Eqs.
+EqMap is a slice-backed map keyed by Eq.
+LGEMap and LGESet are ordered containers keyed by LGE.
+LGEHeap is a priority queue of strings ordered by LGE.
+SortLGEs sorts a slice of LGEs, and MergeSortedLGEs and related functions
+combine sorted slices.
 
 Performance
 
@@ -87,18 +124,33 @@ that
 common), both Eq and LGE symbols are faster than strings if the program
 performs at least as many comparisons as symbol allocations.
 
+Advise, and the internadvise command (in cmd/internadvise) that wraps it,
+repeat these measurements on a sample of a program's own strings.
+
 */
 package intern
 
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 // These constants represent the various error codes the package can return.
 const (
-	ErrTableFull   = iota + 1 // Symbol table is full
-	ErrRemapFailed            // Symbol remapping failed
+	ErrTableFull      = iota + 1 // Symbol table is full
+	ErrRemapFailed               // Symbol remapping failed
+	ErrNoSample                  // No sample strings were provided
+	ErrConflict                  // Reservation conflicts with an existing symbol
+	ErrBadReservation            // Reservation is malformed
+	ErrFrozen                    // Symbol table is frozen
+	ErrBadSnapshot               // Snapshot is malformed
+	ErrOutOfRange                // Value is out of range
+	ErrNotEmpty                  // Symbol table is not empty
+	ErrNotInHeap                 // Item is not in the heap
+	ErrLengthMismatch            // Slices differ in length
+	ErrModeMismatch              // Symbol tables use incompatible modes
+	ErrNotMapped                 // Symbol does not appear in a mapping
 )
 
 // PkgError represents an error specific to the intern package, as opposed to
@@ -133,23 +185,58 @@ func (sl symbolList) Swap(i, j int) { sl[i], sl[j] = sl[j], sl[i] }
 type state struct {
 	symToStr     map[symbol]string // Mapping from symbols to strings
 	strToSym     map[string]symbol // Mapping from strings to symbols
+	strIndex     EqIndex           // Replacement for strToSym or nil
+	prefixTrie   *DATrie           // Index for prefix queries or nil
+	ngrams       *ngramIndex       // Index for substring searches or nil
+	near         *bkTree           // Index for approximate matches or nil
 	tree         *tree             // Tree for maintaining symbols assignments
+	blocks       map[symbol]*tree  // Trees for each block of inline-prefix LGEs
+	prefixLen    int               // Number of bytes inlined into each LGE
 	pending      []string          // Strings not yet mapped to symbols
+	next         symbol            // Next candidate symbol for an Eq
+	ranges       []symRange        // Ranges of Eqs withheld from assignEq
+	hashed       bool              // Whether Eqs are derived from string hashes
+	probes       map[symbol]int    // Probes needed to assign each hashed Eq, if not 0
 	sync.RWMutex                   // Mutex protecting all of the above
+
+	frozen atomic.Pointer[frozenTable] // Read-only mappings or nil if not frozen
+	gen    atomic.Uint64               // Number of times forgetAll was called
+	inline atomic.Bool                 // Whether short strings are packed into Eqs
 }
 
 // forgetAll discards all extant string/symbol mappings and resets the
-// assignment tables to their initial state.
+// assignment tables to their initial, unfrozen state.
 func (st *state) forgetAll() {
+	st.frozen.Store(nil)
+	st.gen.Add(1)
 	st.symToStr = make(map[symbol]string)
 	st.strToSym = make(map[string]symbol)
+	if st.strIndex != nil {
+		st.strIndex.Reset()
+		st.strToSym = nil
+	}
 	st.tree = nil
+	st.blocks = nil
+	st.prefixTrie = nil
+	st.near = nil
+	if st.ngrams != nil {
+		st.ngrams = &ngramIndex{postings: make(map[uint32][]symbol)}
+	}
 	st.pending = make([]string, 0, 100)
+	st.next = 1
+	st.ranges = nil
+	st.probes = nil
 }
 
 // toString converts a symbol back to a string.  It panics if given a symbol
 // that was not created using New*.
 func (st *state) toString(s symbol, ty string) string {
+	if ft := st.frozenTable(); ft != nil {
+		if str, ok := ft.str(s); ok {
+			return str
+		}
+		panic(fmt.Sprintf("%d is not a valid intern.%s", s, ty))
+	}
 	st.RLock()
 	defer st.RUnlock()
 	if str, ok := st.symToStr[s]; ok {
@@ -162,12 +249,27 @@ func (st *state) toString(s symbol, ty string) string {
 // The function returns an error status.
 func (st *state) flushPending() error {
 	var err error
+	if len(st.pending) > 0 && st.frozen.Load() != nil {
+		// A frozen table can't accept new strings.
+		for _, s := range st.pending {
+			if _, ok := st.lookup(s); !ok {
+				st.pending = st.pending[:0]
+				return st.checkFrozen(s, "LGE")
+			}
+		}
+		st.pending = st.pending[:0]
+	}
+	if len(st.pending) > 0 && st.prefixLen > 0 {
+		return st.flushInline()
+	}
 	if len(st.pending) > 0 {
+		var t *tree
 		var sMap map[string]symbol
-		st.tree, sMap, err = st.tree.insertMany(st.pending)
+		t, sMap, err = st.tree.insertMany(st.pending, treeRoot)
 		if err != nil {
 			return err
 		}
+		st.tree = t
 		st.pending = st.pending[:0]
 		for k, v := range sMap {
 			st.strToSym[k] = v
@@ -180,7 +282,7 @@ func (st *state) flushPending() error {
 // getSymbol looks up and returns the symbol associated with a string.  It
 // aborts the program on failure.
 func (st *state) getSymbol(s string) symbol {
-	sym, ok := st.strToSym[s]
+	sym, ok := st.lookup(s)
 	if !ok {
 		panic(fmt.Sprintf("Internal error: Expected to find an interned version of %q", s))
 	}
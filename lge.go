@@ -43,49 +43,166 @@ func PreLGEMulti(ss []string) {
 // non-nil error.  Pre-allocate as many LGEs as possible using PreLGE to reduce
 // the likelihood of that happening.
 func NewLGE(s string) (LGE, error) {
+	return lge.newLGE(s)
+}
+
+// newLGE maps a string to an LGE symbol, returning an error if the string
+// cannot be accommodated.
+func (st *state) newLGE(s string) (LGE, error) {
+	// A frozen table can be read without locking.
+	if ft := st.frozenTable(); ft != nil {
+		if sym, ok := ft.lookup(s); ok {
+			return LGE(sym), nil
+		}
+		return 0, st.checkFrozen(s, "LGE")
+	}
+
 	// Acquire a lock on LGE state.
 	var err error
-	lge.Lock()
-	defer lge.Unlock()
+	st.Lock()
+	defer st.Unlock()
 
 	// Mark the new string as pending then flush all pending symbols.
-	lge.pending = append(lge.pending, s)
-	err = lge.flushPending()
+	st.pending = append(st.pending, s)
+	err = st.flushPending()
 	if err != nil {
 		return 0, err
 	}
 
 	// Return the new symbol
-	return LGE(lge.getSymbol(s)), nil
+	return LGE(st.getSymbol(s)), nil
 }
 
 // NewLGEMulti performs the same operation as NewLGE but accepts a slice of
 // strings instead of an individual string.  This amortizes some costs when
 // allocating a large number of LGEs at once.
 func NewLGEMulti(ss []string) ([]LGE, error) {
+	return lge.newLGEMulti(ss)
+}
+
+// newLGEMulti maps a slice of strings to LGE symbols, returning an error if
+// any string cannot be accommodated.
+func (st *state) newLGEMulti(ss []string) ([]LGE, error) {
+	// A frozen table can be read without locking.
+	syms := make([]LGE, len(ss))
+	if ft := st.frozenTable(); ft != nil {
+		for i, s := range ss {
+			sym, ok := ft.lookup(s)
+			if !ok {
+				return syms, st.checkFrozen(s, "LGE")
+			}
+			syms[i] = LGE(sym)
+		}
+		return syms, nil
+	}
+
 	// Acquire a lock on LGE state.
 	var err error
-	lge.Lock()
-	defer lge.Unlock()
+	st.Lock()
+	defer st.Unlock()
 
 	// Mark all new strings as pending then flush all pending symbols.
-	syms := make([]LGE, len(ss))
 	if len(ss) == 0 {
 		return syms, nil
 	}
-	lge.pending = append(lge.pending, ss...)
-	err = lge.flushPending()
+	st.pending = append(st.pending, ss...)
+	err = st.flushPending()
 	if err != nil {
 		return syms, err
 	}
 
 	// Return the new symbols.
 	for i, s := range ss {
-		syms[i] = LGE(lge.getSymbol(s))
+		syms[i] = LGE(st.getSymbol(s))
 	}
 	return syms, nil
 }
 
+// LookupLGE returns the LGE associated with a string without allocating a
+// new LGE.  The second return value indicates whether the string was found.
+// Strings passed to PreLGE but not yet allocated are not found.
+func LookupLGE(s string) (LGE, bool) {
+	return lge.lookupLGE(s)
+}
+
+// lookupLGE returns the LGE associated with a string without allocating a
+// new LGE.
+func (st *state) lookupLGE(s string) (LGE, bool) {
+	if ft := st.frozenTable(); ft != nil {
+		sym, ok := ft.lookup(s)
+		return LGE(sym), ok
+	}
+	st.RLock()
+	defer st.RUnlock()
+	sym, ok := st.lookup(s)
+	return LGE(sym), ok
+}
+
+// prefixSyms returns the symbols of all allocated strings that begin with a
+// given prefix, in sorted order.  If all is false, it returns only the first
+// and last such symbols.
+func (st *state) prefixSyms(prefix string, all bool) []symbol {
+	if ft := st.frozenTable(); ft != nil {
+		lo, hi := ft.prefixRanks(prefix)
+		if lo == hi {
+			return nil
+		}
+		if !all {
+			return []symbol{ft.symAt(lo), ft.symAt(hi - 1)}
+		}
+		syms := make([]symbol, hi-lo)
+		for i := range syms {
+			syms[i] = ft.symAt(lo + i)
+		}
+		return syms
+	}
+	st.RLock()
+	defer st.RUnlock()
+	if st.prefixLen > 0 {
+		syms := st.scanPrefix(prefix)
+		if !all && len(syms) > 2 {
+			syms = []symbol{syms[0], syms[len(syms)-1]}
+		}
+		return syms
+	}
+	var syms []symbol
+	st.tree.eachPrefix(prefix, func(s string, sym symbol) bool {
+		if all || len(syms) < 2 {
+			syms = append(syms, sym)
+		} else {
+			syms[1] = sym
+		}
+		return true
+	})
+	return syms
+}
+
+// LGEPrefixRange returns the smallest and largest LGEs whose strings begin
+// with a given prefix.  Because LGEs are ordered like their strings, every
+// LGE between the two also begins with the prefix.  The third return value
+// is false if no LGE begins with the prefix.  Strings passed to PreLGE but
+// not yet allocated are not considered.
+func LGEPrefixRange(prefix string) (lo, hi LGE, ok bool) {
+	syms := lge.prefixSyms(prefix, false)
+	if len(syms) == 0 {
+		return 0, 0, false
+	}
+	return LGE(syms[0]), LGE(syms[len(syms)-1]), true
+}
+
+// EachLGE calls a function on each LGE whose string begins with a given
+// prefix, in increasing order, until the function returns false.  An empty
+// prefix visits all LGEs.  The function may itself call other functions in
+// this package.  Strings passed to PreLGE but not yet allocated are not
+// visited.
+func EachLGE(prefix string, f func(LGE) bool) {
+	for _, sym := range lge.prefixSyms(prefix, true) {
+		if !f(LGE(sym)) {
+			return
+		}
+	}
+}
+
 // String converts an LGE back to a string.  It panics if given an LGE that was
 // not created using NewLGE.
 func (s LGE) String() string {
@@ -94,7 +211,8 @@ func (s LGE) String() string {
 
 // ForgetAllLGEs discards all existing mappings from strings to LGEs so the
 // associated memory can be reclaimed.  Use this function only when you know
-// for sure that no previously mapped LGEs will subsequently be used.
+// for sure that no previously mapped LGEs will subsequently be used.  If the
+// LGE table was frozen, ForgetAllLGEs thaws it.
 func ForgetAllLGEs() {
 	lge.Lock()
 	lge.forgetAll()
@@ -104,33 +222,44 @@ func ForgetAllLGEs() {
 // RemapAllLGEs reassigns LGEs to strings to help clean up the mapping.  This
 // provides a way to add strings that were previously rejected by NewLGE.
 // RemapAllLGEs returns a mapping from old LGEs to new LGEs to assist programs
-// with updating LGEs that are in use.
+// with updating LGEs that are in use.  The remapcheck analyzer in the
+// analysis/remapcheck directory reports stored LGEs that a program never
+// updates.
 func RemapAllLGEs() (map[LGE]LGE, error) {
-	// Store the existing LGE state then reinitialize it.
-	lge.Lock()
-	defer lge.Unlock()
-	oldLge := state{
-		pending:  lge.pending,
-		strToSym: lge.strToSym,
+	return lge.remapLGEs()
+}
+
+// remapLGEs reassigns LGEs to strings and returns a mapping from old LGEs to
+// new LGEs.
+func (st *state) remapLGEs() (map[LGE]LGE, error) {
+	// Store the existing state then reinitialize it.
+	st.Lock()
+	defer st.Unlock()
+	if err := st.checkFrozen("", "LGE"); err != nil {
+		return nil, err
 	}
-	lge.forgetAll()
+	oldSt := state{
+		pending:  st.pending,
+		strToSym: st.strToSym,
+	}
+	st.forgetAll()
 
 	// Append the old list of strings to the pending list.
-	lge.pending = oldLge.pending
-	for s := range oldLge.strToSym {
-		lge.pending = append(lge.pending, s)
+	st.pending = oldSt.pending
+	for s := range oldSt.strToSym {
+		st.pending = append(st.pending, s)
 	}
 
 	// Map all pending strings to LGEs.
-	err := lge.flushPending()
+	err := st.flushPending()
 	if err != nil {
 		return nil, err
 	}
 
 	// Construct a map from old to new LGEs and return it.
-	m := make(map[LGE]LGE, len(lge.strToSym))
-	for str, oldSym := range oldLge.strToSym {
-		newSym, ok := lge.strToSym[str]
+	m := make(map[LGE]LGE, len(st.strToSym))
+	for str, oldSym := range oldSt.strToSym {
+		newSym, ok := st.strToSym[str]
 		if !ok {
 			e := &PkgError{
 				Code: ErrRemapFailed,
@@ -3,6 +3,7 @@
 package intern_test
 
 import (
+	"sort"
 	"testing"
 
 	"github.com/spakin/intern"
@@ -104,3 +105,31 @@ func BenchmarkCompareSimilarLGEs(b *testing.B) {
 		}
 	}
 }
+
+// randomLGEs interns a number of long, randomly generated strings as LGEs.
+func randomLGEs(b *testing.B, n int) []intern.LGE {
+	intern.ForgetAllLGEs()
+	strs := generateRandomStrings(n)
+	intern.PreLGEMulti(strs)
+	syms, err := intern.NewLGEMulti(strs)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return syms
+}
+
+// BenchmarkSortRandomLGESlice measures the time needed to sort a number of
+// LGEs using sort.Sort.
+func BenchmarkSortRandomLGESlice(b *testing.B) {
+	syms := randomLGEs(b, b.N)
+	b.ResetTimer()
+	sort.Sort(LGESlice(syms))
+}
+
+// BenchmarkSortRandomLGEs measures the time needed to sort a number of LGEs
+// using SortLGEs.
+func BenchmarkSortRandomLGEs(b *testing.B) {
+	syms := randomLGEs(b, b.N)
+	b.ResetTimer()
+	intern.SortLGEs(syms)
+}
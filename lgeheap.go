@@ -0,0 +1,247 @@
+// This file provides LGEHeap, a priority queue of strings ordered by LGE.
+
+package intern
+
+import "fmt"
+
+// An LGEHeapItem is an entry in an LGEHeap.  It serves as a handle for
+// DecreaseKey and Remove.
+type LGEHeapItem[T any] struct {
+	Value T      // Payload associated with the string
+	key   LGE    // String's LGE as of the heap's generation
+	str   string // String by which the item is prioritized
+	index int    // Position in the heap or -1 if not in the heap
+}
+
+// String returns the string by which an LGEHeapItem is prioritized.
+func (it *LGEHeapItem[T]) String() string {
+	return it.str
+}
+
+// An LGEHeap is a min-heap of strings, each with an associated payload of
+// type T.  Strings are interned to LGEs so they can be compared as
+// integers.  If NewLGE runs out of LGEs while pushing a string, the heap
+// calls RemapAllLGEs and re-keys its own contents, so it never needs to be
+// rebuilt by hand.  Likewise, a heap re-keys itself the next time a string is
+// pushed after ForgetAllLGEs or a RemapAllLGEs performed elsewhere in the
+// program.  The zero value is an empty heap ready to use.  An LGEHeap is not
+// safe for concurrent modification.
+type LGEHeap[T any] struct {
+	// OnRemap, if not nil, is called with the mapping from old LGEs to new
+	// LGEs whenever the heap calls RemapAllLGEs.  Programs that hold LGEs
+	// outside the heap use it to update them.
+	OnRemap func(map[LGE]LGE)
+
+	items []*LGEHeapItem[T] // Binary heap of items
+	gen   uint64            // LGE table generation to which the keys belong
+}
+
+// sync re-keys an LGEHeap if the LGE table has been reset or remapped since
+// the heap's keys were assigned.  Because LGEs are ordered like their strings,
+// re-keying never disturbs the heap order.
+func (h *LGEHeap[T]) sync() error {
+	g := lge.gen.Load()
+	if h.gen == g {
+		return nil
+	}
+	strs := make([]string, len(h.items))
+	for i, it := range h.items {
+		strs[i] = it.str
+	}
+	keys, err := NewLGEMulti(strs)
+	if err != nil {
+		return err
+	}
+	for i, it := range h.items {
+		it.key = keys[i]
+	}
+	h.gen = g
+	return nil
+}
+
+// intern interns a list of strings to LGEs that are comparable with the
+// heap's keys.  If the LGE table is full, intern remaps all LGEs and tries
+// again.
+func (h *LGEHeap[T]) intern(ss []string) ([]LGE, error) {
+	if err := h.sync(); err != nil {
+		return nil, err
+	}
+	keys, err := NewLGEMulti(ss)
+	if pe, ok := err.(*PkgError); ok && pe.Code == ErrTableFull {
+		// Remap all LGEs to make room for the new strings.
+		PreLGEMulti(ss)
+		var m map[LGE]LGE
+		if m, err = RemapAllLGEs(); err != nil {
+			return nil, err
+		}
+		if h.OnRemap != nil {
+			h.OnRemap(m)
+		}
+		if err = h.sync(); err != nil {
+			return nil, err
+		}
+		keys, err = NewLGEMulti(ss)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// less says whether the item at position i precedes the item at position j.
+func (h *LGEHeap[T]) less(i, j int) bool {
+	return h.items[i].key < h.items[j].key
+}
+
+// swap swaps the items at positions i and j.
+func (h *LGEHeap[T]) swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+// up moves the item at position i toward the root until the heap is ordered.
+func (h *LGEHeap[T]) up(i int) {
+	for i > 0 {
+		p := (i - 1) / 2
+		if !h.less(i, p) {
+			break
+		}
+		h.swap(i, p)
+		i = p
+	}
+}
+
+// down moves the item at position i toward the leaves until the heap is
+// ordered.  It returns true if the item moved.
+func (h *LGEHeap[T]) down(i int) bool {
+	i0 := i
+	n := len(h.items)
+	for {
+		c := 2*i + 1
+		if c >= n {
+			break
+		}
+		if c+1 < n && h.less(c+1, c) {
+			c++
+		}
+		if !h.less(c, i) {
+			break
+		}
+		h.swap(i, c)
+		i = c
+	}
+	return i > i0
+}
+
+// Len returns the number of items in an LGEHeap.
+func (h *LGEHeap[T]) Len() int {
+	return len(h.items)
+}
+
+// Push interns a string and adds it to an LGEHeap along with a payload.  It
+// returns a handle to the new item.  If the LGE table has no room for the
+// string, Push calls RemapAllLGEs, which changes every LGE in the program;
+// set OnRemap to learn the new LGEs.
+func (h *LGEHeap[T]) Push(s string, v T) (*LGEHeapItem[T], error) {
+	keys, err := h.intern([]string{s})
+	if err != nil {
+		return nil, err
+	}
+	it := &LGEHeapItem[T]{Value: v, key: keys[0], str: s, index: len(h.items)}
+	h.items = append(h.items, it)
+	h.up(it.index)
+	return it, nil
+}
+
+// PushMulti interns a list of strings and adds them to an LGEHeap along with
+// a corresponding list of payloads of the same length.  It returns handles to
+// the new items.  PushMulti interns all of the strings at once then heapifies
+// the result in linear time, which is faster than pushing the strings one at
+// a time.  Like Push, it may call RemapAllLGEs.
+func (h *LGEHeap[T]) PushMulti(ss []string, vs []T) ([]*LGEHeapItem[T], error) {
+	if len(ss) != len(vs) {
+		return nil, &PkgError{
+			Code: ErrLengthMismatch,
+			msg:  fmt.Sprintf("PushMulti was given %d strings but %d payloads", len(ss), len(vs)),
+		}
+	}
+	keys, err := h.intern(ss)
+	if err != nil {
+		return nil, err
+	}
+	its := make([]*LGEHeapItem[T], len(ss))
+	for i, s := range ss {
+		its[i] = &LGEHeapItem[T]{Value: vs[i], key: keys[i], str: s, index: len(h.items)}
+		h.items = append(h.items, its[i])
+	}
+	for i := len(h.items)/2 - 1; i >= 0; i-- {
+		h.down(i)
+	}
+	return its, nil
+}
+
+// Peek returns the item with the smallest string in an LGEHeap without
+// removing it.  It returns nil if the heap is empty.
+func (h *LGEHeap[T]) Peek() *LGEHeapItem[T] {
+	if len(h.items) == 0 {
+		return nil
+	}
+	return h.items[0]
+}
+
+// Pop removes and returns the item with the smallest string in an LGEHeap.
+// It returns nil if the heap is empty.
+func (h *LGEHeap[T]) Pop() *LGEHeapItem[T] {
+	if len(h.items) == 0 {
+		return nil
+	}
+	return h.Remove(h.items[0])
+}
+
+// Remove removes an item from an LGEHeap and returns it.  It returns nil if
+// the item is not in the heap.
+func (h *LGEHeap[T]) Remove(it *LGEHeapItem[T]) *LGEHeapItem[T] {
+	i := it.index
+	if i < 0 || i >= len(h.items) || h.items[i] != it {
+		return nil
+	}
+	n := len(h.items) - 1
+	if i != n {
+		h.swap(i, n)
+	}
+	h.items[n] = nil
+	h.items = h.items[:n]
+	if i != n && !h.down(i) {
+		h.up(i)
+	}
+	it.index = -1
+	return it
+}
+
+// DecreaseKey changes the string by which an item in an LGEHeap is
+// prioritized to a string that precedes it, moving the item toward the front
+// of the heap.  DecreaseKey also accepts a string that follows the item's
+// current string, in which case it moves the item toward the back of the
+// heap.  DecreaseKey returns an error if the item is not in the heap, for
+// example because it was already popped.  Like Push, it may call
+// RemapAllLGEs.
+func (h *LGEHeap[T]) DecreaseKey(it *LGEHeapItem[T], s string) error {
+	i := it.index
+	if i < 0 || i >= len(h.items) || h.items[i] != it {
+		return &PkgError{
+			Code: ErrNotInHeap,
+			Str:  it.str,
+			msg:  fmt.Sprintf("Item %q is not in the heap", it.str),
+		}
+	}
+	keys, err := h.intern([]string{s})
+	if err != nil {
+		return err
+	}
+	it.key, it.str = keys[0], s
+	if !h.down(i) {
+		h.up(i)
+	}
+	return nil
+}
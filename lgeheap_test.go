@@ -0,0 +1,149 @@
+// This file provides unit tests for LGEHeap.
+
+package intern_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// drainLGEHeap pops all items from an LGEHeap, ensuring that they appear in
+// order and that each string's payload is its position in a list.
+func drainLGEHeap(t *testing.T, h *intern.LGEHeap[int], strs []string) []string {
+	var popped []string
+	for h.Len() > 0 {
+		it := h.Pop()
+		s := it.String()
+		if len(popped) > 0 && s < popped[len(popped)-1] {
+			t.Fatalf("%q was popped after %q", s, popped[len(popped)-1])
+		}
+		if strs[it.Value] != s {
+			t.Fatalf("Expected %q to have payload %d", s, it.Value)
+		}
+		popped = append(popped, s)
+	}
+	if h.Pop() != nil {
+		t.Fatal("Popped an item from an empty heap")
+	}
+	return popped
+}
+
+// TestLGEHeapPush pushes strings in reverse order, which exhausts the LGE
+// table, and ensures they are popped in order and that OnRemap keeps an LGE
+// held outside the heap up to date.
+func TestLGEHeapPush(t *testing.T) {
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	strs := make([]string, len(ozChars))
+	copy(strs, ozChars)
+	sort.Sort(sort.Reverse(sort.StringSlice(strs)))
+	var h intern.LGEHeap[int]
+	var held intern.LGE
+	remaps := 0
+	h.OnRemap = func(m map[intern.LGE]intern.LGE) {
+		held = m[held]
+		remaps++
+	}
+	for i, s := range strs {
+		if _, err := h.Push(s, i); err != nil {
+			t.Fatal(err)
+		}
+		if i == 10 {
+			// Force the heap to re-key itself.
+			intern.ForgetAllLGEs()
+			var err error
+			if held, err = intern.NewLGE("Toto"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if remaps == 0 {
+		t.Fatal("OnRemap was never called")
+	}
+	if e, ok := intern.LookupLGE("Toto"); !ok || e != held {
+		t.Fatalf("OnRemap failed to translate the LGE of %q", "Toto")
+	}
+	if h.Len() != len(strs) {
+		t.Fatalf("Expected %d items but saw %d", len(strs), h.Len())
+	}
+	if h.Peek().String() != strs[len(strs)-1] {
+		t.Fatalf("Expected %q at the front but saw %q", strs[len(strs)-1], h.Peek())
+	}
+	if popped := drainLGEHeap(t, &h, strs); len(popped) != len(strs) {
+		t.Fatalf("Expected %d items but saw %d", len(strs), len(popped))
+	}
+}
+
+// TestLGEHeapUpdate tests heapifying, decreasing keys, and removing items.
+func TestLGEHeapUpdate(t *testing.T) {
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	strs := generateRandomStrings(5000)
+	vals := make([]int, len(strs))
+	for i := range vals {
+		vals[i] = i
+	}
+	var h intern.LGEHeap[int]
+	its, err := h.PushMulti(strs, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove some items and move others to the front of the heap.
+	prng := rand.New(rand.NewSource(6767)) // Constant for reproducibility
+	removed := make(map[string]struct{})
+	for i := 0; i < 1000; i++ {
+		it := its[prng.Intn(len(its))]
+		if prng.Intn(2) == 0 {
+			if h.Remove(it) != nil {
+				removed[it.String()] = struct{}{}
+			}
+			continue
+		}
+		if _, gone := removed[it.String()]; gone {
+			continue
+		}
+		s := "!" + it.String()
+		strs[it.Value] = s
+		if err = h.DecreaseKey(it, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	popped := drainLGEHeap(t, &h, strs)
+	if len(popped) != len(strs)-len(removed) {
+		t.Fatalf("Expected %d items but saw %d", len(strs)-len(removed), len(popped))
+	}
+}
+
+// TestLGEHeapErrors ensures that an LGEHeap rejects mismatched payloads and
+// items that are not in the heap.
+func TestLGEHeapErrors(t *testing.T) {
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	var h intern.LGEHeap[int]
+	if _, err := h.PushMulti(ozChars[:5], []int{1, 2, 3}); err == nil {
+		t.Fatal("PushMulti accepted fewer payloads than strings")
+	}
+	if h.Len() != 0 {
+		t.Fatalf("Expected an empty heap but saw %d items", h.Len())
+	}
+	its, err := h.PushMulti(ozChars[:5], []int{0, 1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := h.Pop()
+	err = h.DecreaseKey(it, "!"+it.String())
+	if pe, ok := err.(*intern.PkgError); !ok || pe.Code != intern.ErrNotInHeap {
+		t.Fatalf("Expected ErrNotInHeap but saw %v", err)
+	}
+	for _, other := range its {
+		if other != it {
+			if err = h.DecreaseKey(other, "!"+other.String()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
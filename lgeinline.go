@@ -0,0 +1,134 @@
+// This file provides an LGE encoding that derives each LGE's high-order bits
+// directly from the leading bytes of its string.
+
+package intern
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxLGEPrefix is the largest number of bytes SetLGEInlinePrefix accepts.
+const maxLGEPrefix = 7
+
+// lgeBlock returns the symbol at the base of the block of symbols reserved
+// for strings that begin with the same n bytes as a given string.  The
+// string's first n bytes, padded with zeros, occupy the high 8n bits of the
+// symbol, most significant byte first.
+func lgeBlock(s string, n int) symbol {
+	var b symbol
+	for i := 0; i < n; i++ {
+		b <<= 8
+		if i < len(s) {
+			b |= symbol(s[i])
+		}
+	}
+	return b << (64 - 8*n)
+}
+
+// lgeBlockRoot returns the symbol assigned to the root of the tree of
+// strings longer than n bytes in the block with a given base.  The tree spans
+// the upper half of the block, leaving the lower half for strings of at most
+// n bytes.
+func lgeBlockRoot(base symbol, n int) symbol {
+	return base | 3<<(62-8*n)
+}
+
+// inlineLGE returns the symbol of a string of at most n bytes.  Such strings
+// follow all shorter strings in the same block, all of which are prefixes of
+// them, and precede all longer strings in the block, of which they are
+// prefixes.
+func inlineLGE(s string, n int) symbol {
+	return lgeBlock(s, n) + symbol(len(s)) + 1
+}
+
+// flushInline flushes all pending symbols in a state that uses inline
+// prefixes, assigning strings of at most prefixLen bytes their inline symbols
+// and inserting longer strings into their blocks' trees.
+func (st *state) flushInline() error {
+	n := st.prefixLen
+	groups := make(map[symbol][]string)
+	for _, s := range st.pending {
+		if _, ok := st.strToSym[s]; ok {
+			continue
+		}
+		if len(s) <= n {
+			sym := inlineLGE(s, n)
+			st.strToSym[s] = sym
+			st.symToStr[sym] = s
+			continue
+		}
+		b := lgeBlock(s, n)
+		groups[b] = append(groups[b], s)
+	}
+	if st.blocks == nil {
+		st.blocks = make(map[symbol]*tree)
+	}
+	for b, ss := range groups {
+		// Keep the block's existing tree if the insertion fails.
+		t, sMap, err := st.blocks[b].insertMany(ss, lgeBlockRoot(b, n))
+		if err != nil {
+			return err
+		}
+		st.blocks[b] = t
+		for k, v := range sMap {
+			st.strToSym[k] = v
+			st.symToStr[v] = k
+		}
+	}
+	st.pending = st.pending[:0]
+	return nil
+}
+
+// scanPrefix returns the symbols of all allocated strings that begin with a
+// given prefix, in sorted order, by examining every string.  The caller must
+// hold the state's lock.
+func (st *state) scanPrefix(prefix string) []symbol {
+	var syms []symbol
+	for sym, s := range st.symToStr {
+		if strings.HasPrefix(s, prefix) {
+			syms = append(syms, sym)
+		}
+	}
+	sort.Sort(symbolList(syms))
+	return syms
+}
+
+// SetLGEInlinePrefix makes the first n bytes of each string, for n from 1 to
+// 7, determine the high 8n bits of the string's LGE.  Strings of at most n
+// bytes are then assigned LGEs derived directly from their bytes, so they
+// never cause NewLGE to fail, no matter the order in which they are
+// allocated.  Longer strings are allocated as usual but only against other
+// strings that share their first n bytes, within a block of 2^(63-8n) LGEs.
+// Larger values of n therefore suit programs with many short strings, and
+// smaller values suit programs with many long strings that begin alike.  An
+// n of 7 leaves each block only a 7-level tree of LGEs, so a block may fill
+// after only a few strings that share a prefix are allocated one at a time.
+// An n of 0, the default, disables inline prefixes.
+//
+// SetLGEInlinePrefix returns an error unless the LGE table is empty and
+// unfrozen.  The setting survives ForgetAllLGEs and RemapAllLGEs.  While
+// inline prefixes are enabled, ReserveLGEs is unavailable, and EachLGE and
+// LGEPrefixRange examine every LGE.
+func SetLGEInlinePrefix(n int) error {
+	lge.Lock()
+	defer lge.Unlock()
+	if n < 0 || n > maxLGEPrefix {
+		return &PkgError{
+			Code: ErrOutOfRange,
+			msg:  fmt.Sprintf("Inline prefix length %d is not between 0 and %d", n, maxLGEPrefix),
+		}
+	}
+	if err := lge.checkFrozen("", "LGE"); err != nil {
+		return err
+	}
+	if len(lge.symToStr) > 0 || len(lge.pending) > 0 {
+		return &PkgError{
+			Code: ErrNotEmpty,
+			msg:  "Unable to change the LGE inline prefix; the LGE table is not empty",
+		}
+	}
+	lge.prefixLen = n
+	return nil
+}
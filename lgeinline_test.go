@@ -0,0 +1,182 @@
+// This file provides unit tests for inline LGE prefixes.
+
+package intern_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// useLGEInlinePrefix empties the LGE table and selects a given inline prefix
+// length.
+func useLGEInlinePrefix(t testing.TB, n int) {
+	intern.ForgetAllLGEs()
+	if err := intern.SetLGEInlinePrefix(n); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkLGEOrder ensures that a set of LGEs is ordered like their strings.
+func checkLGEOrder(t *testing.T, strs []string, syms []intern.LGE) {
+	t.Helper()
+	idx := make([]int, len(strs))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return strs[idx[i]] < strs[idx[j]] })
+	for k := 1; k < len(idx); k++ {
+		i, j := idx[k-1], idx[k]
+		if strs[i] < strs[j] && syms[i] >= syms[j] {
+			t.Fatalf("%q < %q but LGE %d >= LGE %d", strs[i], strs[j], syms[i], syms[j])
+		}
+		if strs[i] == strs[j] && syms[i] != syms[j] {
+			t.Fatalf("%q maps to both %d and %d", strs[i], syms[i], syms[j])
+		}
+	}
+	for i, s := range strs {
+		if syms[i].String() != s {
+			t.Fatalf("Expected %q but saw %q", s, syms[i])
+		}
+	}
+}
+
+// TestLGEInlineShort ensures that short strings allocated in the worst
+// possible order never exhaust the LGE table.
+func TestLGEInlineShort(t *testing.T) {
+	useLGEInlinePrefix(t, 7)
+	defer useLGEInlinePrefix(t, 0)
+	strs := make([]string, 5000)
+	syms := make([]intern.LGE, len(strs))
+	for i := range strs {
+		strs[i] = fmt.Sprintf("%07d", len(strs)-i)
+		var err error
+		syms[i], err = intern.NewLGE(strs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkLGEOrder(t, strs, syms)
+
+	// The inline prefix can't change while the table is in use.
+	var pe *intern.PkgError
+	if err := intern.SetLGEInlinePrefix(3); !errors.As(err, &pe) || pe.Code != intern.ErrNotEmpty {
+		t.Fatalf("Expected a nonempty-table error but saw %v", err)
+	}
+	if err := intern.SetLGEInlinePrefix(8); !errors.As(err, &pe) || pe.Code != intern.ErrOutOfRange {
+		t.Fatalf("Expected an out-of-range error but saw %v", err)
+	}
+}
+
+// TestLGEInlineMixed ensures that short and long strings, including strings
+// with embedded zero bytes, are ordered correctly for a variety of inline
+// prefix lengths.
+func TestLGEInlineMixed(t *testing.T) {
+	defer useLGEInlinePrefix(t, 0)
+	prng := rand.New(rand.NewSource(7171)) // Constant for reproducibility
+	const alphabet = "\x00\x01ab\xff"
+	for n := 1; n <= 7; n++ {
+		useLGEInlinePrefix(t, n)
+		strs := make([]string, 2000)
+		for i := range strs {
+			buf := make([]byte, prng.Intn(12))
+			for j := range buf {
+				buf[j] = alphabet[prng.Intn(len(alphabet))]
+			}
+			strs[i] = string(buf)
+		}
+		intern.PreLGEMulti(strs[:1000])
+		syms, err := intern.NewLGEMulti(strs[:1000])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range strs[1000:] {
+			sym, err := intern.NewLGE(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			syms = append(syms, sym)
+		}
+		checkLGEOrder(t, strs, syms)
+
+		// Remapping preserves the order.
+		m, err := intern.RemapAllLGEs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range syms {
+			syms[i] = m[syms[i]]
+		}
+		checkLGEOrder(t, strs, syms)
+
+		// Prefix queries see every string.
+		var want []string
+		for _, s := range strs {
+			if len(s) > 0 && s[0] == 'a' {
+				want = append(want, s)
+			}
+		}
+		sort.Strings(want)
+		var got []string
+		intern.EachLGE("a", func(l intern.LGE) bool {
+			got = append(got, l.String())
+			return true
+		})
+		want = dedupStrings(want)
+		if len(got) != len(want) || (len(got) > 0 && got[0] != want[0]) {
+			t.Fatalf("Expected %d strings beginning with \"a\" but saw %d", len(want), len(got))
+		}
+	}
+}
+
+// dedupStrings removes adjacent duplicates from a sorted slice of strings.
+func dedupStrings(ss []string) []string {
+	var out []string
+	for i, s := range ss {
+		if i == 0 || s != ss[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// TestLGEInlineFullBlock fills a block of LGEs by interning strings that
+// share a prefix one at a time and ensures that a failure to intern a string
+// neither corrupts the block nor reuses LGEs that were already issued.
+func TestLGEInlineFullBlock(t *testing.T) {
+	useLGEInlinePrefix(t, 7)
+	defer useLGEInlinePrefix(t, 0)
+	issued := make(map[intern.LGE]string)
+	fails := 0
+	for i := 0; i < 40; i++ {
+		s := fmt.Sprintf("abcdefg%03d", i)
+		l, err := intern.NewLGE(s)
+		if err != nil {
+			var pe *intern.PkgError
+			if !errors.As(err, &pe) || pe.Code != intern.ErrTableFull {
+				t.Fatalf("Expected ErrTableFull but saw %v", err)
+			}
+			fails++
+			continue
+		}
+		if s2, dup := issued[l]; dup && s2 != s {
+			t.Fatalf("%q and %q were both assigned LGE %d", s2, s, l)
+		}
+		issued[l] = s
+	}
+	if fails == 0 {
+		t.Fatal("Expected a 7-byte prefix to leave room for only a few strings")
+	}
+	for l, s := range issued {
+		if l.String() != s {
+			t.Fatalf("Expected LGE %d to map to %q but saw %q", l, s, l)
+		}
+		if l2, ok := intern.LookupLGE(s); !ok || l2 != l {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, l, l2)
+		}
+	}
+}
@@ -0,0 +1,543 @@
+// This file provides LGEMap and LGESet, ordered containers keyed by LGE.
+
+package intern
+
+import "sort"
+
+// lgeDegree is the minimum number of children of each interior node of an
+// lgeTree other than the root.
+const lgeDegree = 16
+
+// Nodes of an lgeTree other than the root contain between lgeMinItems and
+// lgeMaxItems items.
+const (
+	lgeMinItems = lgeDegree - 1
+	lgeMaxItems = 2*lgeDegree - 1
+)
+
+// An lgeItem is a single key/value pair in an lgeTree.
+type lgeItem[V any] struct {
+	key LGE    // Key as of the tree's generation
+	str string // String the key represents
+	val V      // Value associated with the key
+}
+
+// An lgeNode is a node in an lgeTree.
+type lgeNode[V any] struct {
+	items    []lgeItem[V]  // Items in increasing order of key
+	children []*lgeNode[V] // Children or nil for a leaf
+}
+
+// An lgeTree is a B-tree keyed by LGE.  Because LGEs are ordered like their
+// strings, a remapping of LGEs changes an lgeTree's keys but not the order
+// of its items.  An lgeTree therefore records its keys' strings and the LGE
+// table generation to which its keys belong, and re-keys itself in place
+// when it notices that the generation has changed.
+type lgeTree[V any] struct {
+	root *lgeNode[V] // Root of the tree
+	n    int         // Number of items in the tree
+	gen  uint64      // LGE table generation to which the keys belong
+	err  error       // Error from the most recent attempt to re-key
+}
+
+// find returns the index of the first item in a node whose key is not less
+// than a given key and whether that item's key is equal to the given key.
+func (n *lgeNode[V]) find(k LGE) (int, bool) {
+	i := sort.Search(len(n.items), func(i int) bool { return n.items[i].key >= k })
+	return i, i < len(n.items) && n.items[i].key == k
+}
+
+// leaf says whether a node is a leaf.
+func (n *lgeNode[V]) leaf() bool {
+	return len(n.children) == 0
+}
+
+// insertItem inserts an item into a node's item list at a given index.
+func (n *lgeNode[V]) insertItem(i int, it lgeItem[V]) {
+	var zero lgeItem[V]
+	n.items = append(n.items, zero)
+	copy(n.items[i+1:], n.items[i:])
+	n.items[i] = it
+}
+
+// removeItem removes and returns the item at a given index in a node's item
+// list.
+func (n *lgeNode[V]) removeItem(i int) lgeItem[V] {
+	var zero lgeItem[V]
+	it := n.items[i]
+	copy(n.items[i:], n.items[i+1:])
+	n.items[len(n.items)-1] = zero
+	n.items = n.items[:len(n.items)-1]
+	return it
+}
+
+// insertChild inserts a child into a node's child list at a given index.
+func (n *lgeNode[V]) insertChild(i int, c *lgeNode[V]) {
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = c
+}
+
+// removeChild removes and returns the child at a given index in a node's
+// child list.
+func (n *lgeNode[V]) removeChild(i int) *lgeNode[V] {
+	c := n.children[i]
+	copy(n.children[i:], n.children[i+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+	return c
+}
+
+// split splits a node at a given index, returning the item at that index and
+// a new node containing everything after it.
+func (n *lgeNode[V]) split(i int) (lgeItem[V], *lgeNode[V]) {
+	it := n.items[i]
+	next := &lgeNode[V]{items: append([]lgeItem[V](nil), n.items[i+1:]...)}
+	clear(n.items[i:])
+	n.items = n.items[:i]
+	if !n.leaf() {
+		next.children = append([]*lgeNode[V](nil), n.children[i+1:]...)
+		clear(n.children[i+1:])
+		n.children = n.children[:i+1]
+	}
+	return it, next
+}
+
+// set associates a value with a key in the subtree rooted at a node, which
+// must not be full.  It returns true if the key is new.
+func (n *lgeNode[V]) set(k LGE, v V) bool {
+	i, found := n.find(k)
+	if found {
+		n.items[i].val = v
+		return false
+	}
+	if n.leaf() {
+		n.insertItem(i, lgeItem[V]{key: k, str: k.String(), val: v})
+		return true
+	}
+	if len(n.children[i].items) >= lgeMaxItems {
+		mid, next := n.children[i].split(lgeMaxItems / 2)
+		n.insertItem(i, mid)
+		n.insertChild(i+1, next)
+		switch {
+		case k == mid.key:
+			n.items[i].val = v
+			return false
+		case k > mid.key:
+			i++
+		}
+	}
+	return n.children[i].set(k, v)
+}
+
+// growChild ensures that a node's ith child contains more than the minimum
+// number of items by borrowing an item from a sibling or, if neither
+// sibling can spare one, by merging the child with a sibling.
+func (n *lgeNode[V]) growChild(i int) {
+	switch {
+	case i > 0 && len(n.children[i-1].items) > lgeMinItems:
+		// Borrow from the left sibling.
+		child, left := n.children[i], n.children[i-1]
+		child.insertItem(0, n.items[i-1])
+		n.items[i-1] = left.removeItem(len(left.items) - 1)
+		if !left.leaf() {
+			child.insertChild(0, left.removeChild(len(left.children)-1))
+		}
+	case i < len(n.items) && len(n.children[i+1].items) > lgeMinItems:
+		// Borrow from the right sibling.
+		child, right := n.children[i], n.children[i+1]
+		child.items = append(child.items, n.items[i])
+		n.items[i] = right.removeItem(0)
+		if !right.leaf() {
+			child.children = append(child.children, right.removeChild(0))
+		}
+	default:
+		// Merge with a sibling.
+		if i >= len(n.items) {
+			i--
+		}
+		child := n.children[i]
+		mid := n.removeItem(i)
+		next := n.removeChild(i + 1)
+		child.items = append(child.items, mid)
+		child.items = append(child.items, next.items...)
+		child.children = append(child.children, next.children...)
+	}
+}
+
+// removeMax removes and returns the item with the largest key in the
+// subtree rooted at a node, which must contain more than the minimum number
+// of items.
+func (n *lgeNode[V]) removeMax() lgeItem[V] {
+	for !n.leaf() {
+		i := len(n.items)
+		if len(n.children[i].items) <= lgeMinItems {
+			n.growChild(i)
+			continue
+		}
+		n = n.children[i]
+	}
+	return n.removeItem(len(n.items) - 1)
+}
+
+// remove removes a key from the subtree rooted at a node, which must contain
+// more than the minimum number of items unless it is the root.  It returns
+// true if the key was present.
+func (n *lgeNode[V]) remove(k LGE) bool {
+	for {
+		i, found := n.find(k)
+		if n.leaf() {
+			if found {
+				n.removeItem(i)
+			}
+			return found
+		}
+		if len(n.children[i].items) <= lgeMinItems {
+			n.growChild(i)
+			continue
+		}
+		if found {
+			n.items[i] = n.children[i].removeMax()
+			return true
+		}
+		n = n.children[i]
+	}
+}
+
+// ascend calls a function on each item in the subtree rooted at a node whose
+// key lies in [lo, hi], in increasing order of key.  It stops early, and
+// returns false, if the function returns false or if it encounters a key
+// greater than hi.
+func (n *lgeNode[V]) ascend(lo, hi LGE, f func(it *lgeItem[V]) bool) bool {
+	i, _ := n.find(lo)
+	for ; i < len(n.items); i++ {
+		if !n.leaf() && !n.children[i].ascend(lo, hi, f) {
+			return false
+		}
+		if n.items[i].key > hi || !f(&n.items[i]) {
+			return false
+		}
+	}
+	if !n.leaf() {
+		return n.children[i].ascend(lo, hi, f)
+	}
+	return true
+}
+
+// sync re-keys an lgeTree if the LGE table has been reset or remapped since
+// the tree's keys were assigned.  It returns an error, and leaves the tree
+// out of date, if the tree's strings can no longer be interned.  A later call
+// tries again.
+func (t *lgeTree[V]) sync() error {
+	g := lge.gen.Load()
+	if t.gen == g {
+		return nil
+	}
+	if t.root == nil {
+		t.gen, t.err = g, nil
+		return nil
+	}
+	strs := make([]string, 0, t.n)
+	t.root.ascend(0, ^LGE(0), func(it *lgeItem[V]) bool {
+		strs = append(strs, it.str)
+		return true
+	})
+	keys, err := NewLGEMulti(strs)
+	if err != nil {
+		t.err = err
+		return err
+	}
+	t.gen, t.err = g, nil
+	i := 0
+	t.root.ascend(0, ^LGE(0), func(it *lgeItem[V]) bool {
+		it.key = keys[i]
+		i++
+		return true
+	})
+	return nil
+}
+
+// size returns the number of items in the tree or 0 if the keys cannot be
+// brought up to date.
+func (t *lgeTree[V]) size() int {
+	if t.sync() != nil {
+		return 0
+	}
+	return t.n
+}
+
+// get returns the item with a given key or nil if the key is not present.
+func (t *lgeTree[V]) get(k LGE) *lgeItem[V] {
+	if t.sync() != nil {
+		return nil
+	}
+	for n := t.root; n != nil; {
+		i, found := n.find(k)
+		if found {
+			return &n.items[i]
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return nil
+}
+
+// set associates a value with a key.
+func (t *lgeTree[V]) set(k LGE, v V) error {
+	if err := t.sync(); err != nil {
+		return err
+	}
+	if t.root == nil {
+		t.root = &lgeNode[V]{}
+	}
+	if len(t.root.items) >= lgeMaxItems {
+		mid, next := t.root.split(lgeMaxItems / 2)
+		t.root = &lgeNode[V]{
+			items:    []lgeItem[V]{mid},
+			children: []*lgeNode[V]{t.root, next},
+		}
+	}
+	if t.root.set(k, v) {
+		t.n++
+	}
+	return nil
+}
+
+// remove removes a key and its value.
+func (t *lgeTree[V]) remove(k LGE) error {
+	if err := t.sync(); err != nil {
+		return err
+	}
+	if t.root == nil || !t.root.remove(k) {
+		return nil
+	}
+	t.n--
+	if len(t.root.items) == 0 {
+		if t.root.leaf() {
+			t.root = nil
+		} else {
+			t.root = t.root.children[0]
+		}
+	}
+	return nil
+}
+
+// floor returns the item with the largest key not greater than a given key
+// or nil if there is no such item.
+func (t *lgeTree[V]) floor(k LGE) *lgeItem[V] {
+	if t.sync() != nil {
+		return nil
+	}
+	var best *lgeItem[V]
+	for n := t.root; n != nil; {
+		i, found := n.find(k)
+		if found {
+			return &n.items[i]
+		}
+		if i > 0 {
+			best = &n.items[i-1]
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return best
+}
+
+// ceiling returns the item with the smallest key not less than a given key
+// or nil if there is no such item.
+func (t *lgeTree[V]) ceiling(k LGE) *lgeItem[V] {
+	if t.sync() != nil {
+		return nil
+	}
+	var best *lgeItem[V]
+	for n := t.root; n != nil; {
+		i, found := n.find(k)
+		if found {
+			return &n.items[i]
+		}
+		if i < len(n.items) {
+			best = &n.items[i]
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return best
+}
+
+// ascend calls a function on each item whose key lies in [lo, hi], in
+// increasing order of key, until the function returns false.
+func (t *lgeTree[V]) ascend(lo, hi LGE, f func(it *lgeItem[V]) bool) {
+	if t.sync() != nil {
+		return
+	}
+	if t.root != nil && lo <= hi {
+		t.root.ascend(lo, hi, f)
+	}
+}
+
+// An LGEMap is an ordered map from LGEs to values of type V.  It is
+// implemented as a B-tree and therefore compares keys as integers rather
+// than as strings.  Unlike an ordinary map[LGE]V, an LGEMap remembers the
+// string behind each key, and after RemapAllLGEs or ForgetAllLGEs it
+// automatically replaces its keys with the strings' new LGEs, interning
+// them again if necessary.  Keys passed to an LGEMap must therefore always
+// be current.  If the strings cannot be interned again (for example, because
+// the LGE table is frozen), Set and Delete return the error, the other
+// methods behave as though the map were empty, and Err reports the error,
+// until a later call succeeds in re-keying the map.  An LGEMap works only
+// with LGEs from the global LGE table, not with those of an LGETable.  The
+// zero value is an empty map ready to use.  An LGEMap is not safe for
+// concurrent modification.
+type LGEMap[V any] struct {
+	t lgeTree[V]
+}
+
+// Get returns the value associated with an LGE.  The second return value
+// indicates whether the LGE is present.
+func (m *LGEMap[V]) Get(k LGE) (V, bool) {
+	if it := m.t.get(k); it != nil {
+		return it.val, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Set associates a value with an LGE.  It returns an error if the map's
+// existing keys could not be brought up to date.
+func (m *LGEMap[V]) Set(k LGE, v V) error {
+	return m.t.set(k, v)
+}
+
+// Delete removes an LGE and its value from an LGEMap.  It returns an error if
+// the map's existing keys could not be brought up to date.
+func (m *LGEMap[V]) Delete(k LGE) error {
+	return m.t.remove(k)
+}
+
+// Len returns the number of LGEs in an LGEMap.
+func (m *LGEMap[V]) Len() int {
+	return m.t.size()
+}
+
+// Err returns the error that prevented the most recent attempt to bring an
+// LGEMap's keys up to date or nil if the keys are current.
+func (m *LGEMap[V]) Err() error {
+	return m.t.err
+}
+
+// Floor returns the largest LGE in an LGEMap that is less than or equal to
+// a given LGE, along with its value.  The third return value is false if
+// there is no such LGE.
+func (m *LGEMap[V]) Floor(k LGE) (LGE, V, bool) {
+	if it := m.t.floor(k); it != nil {
+		return it.key, it.val, true
+	}
+	var zero V
+	return 0, zero, false
+}
+
+// Ceiling returns the smallest LGE in an LGEMap that is greater than or
+// equal to a given LGE, along with its value.  The third return value is
+// false if there is no such LGE.
+func (m *LGEMap[V]) Ceiling(k LGE) (LGE, V, bool) {
+	if it := m.t.ceiling(k); it != nil {
+		return it.key, it.val, true
+	}
+	var zero V
+	return 0, zero, false
+}
+
+// Range calls a function on each LGE in an LGEMap from lo to hi inclusive
+// and its value, in increasing order, until the function returns false.
+// The bounds need not be present in the map.  Combine Range with
+// LGEPrefixRange to visit all keys that begin with a given prefix.  The
+// function must not modify the map.
+func (m *LGEMap[V]) Range(lo, hi LGE, f func(k LGE, v V) bool) {
+	m.t.ascend(lo, hi, func(it *lgeItem[V]) bool {
+		return f(it.key, it.val)
+	})
+}
+
+// Each calls a function on each LGE in an LGEMap and its value, in
+// increasing order, until the function returns false.  The function must
+// not modify the map.
+func (m *LGEMap[V]) Each(f func(k LGE, v V) bool) {
+	m.Range(0, ^LGE(0), f)
+}
+
+// An LGESet is an ordered set of LGEs.  Like an LGEMap, it is implemented
+// as a B-tree, automatically replaces its LGEs after RemapAllLGEs or
+// ForgetAllLGEs, reports failures to do so through Add, Remove, and Err, and
+// works only with LGEs from the global LGE table.  The zero value is an empty
+// set ready to use.  An LGESet is not safe for concurrent modification.
+type LGESet struct {
+	t lgeTree[struct{}]
+}
+
+// Add adds an LGE to an LGESet.  It returns an error if the set's existing
+// LGEs could not be brought up to date.
+func (s *LGESet) Add(k LGE) error {
+	return s.t.set(k, struct{}{})
+}
+
+// Remove removes an LGE from an LGESet.  It returns an error if the set's
+// existing LGEs could not be brought up to date.
+func (s *LGESet) Remove(k LGE) error {
+	return s.t.remove(k)
+}
+
+// Contains says whether an LGESet contains a given LGE.
+func (s *LGESet) Contains(k LGE) bool {
+	return s.t.get(k) != nil
+}
+
+// Len returns the number of LGEs in an LGESet.
+func (s *LGESet) Len() int {
+	return s.t.size()
+}
+
+// Err returns the error that prevented the most recent attempt to bring an
+// LGESet's LGEs up to date or nil if the LGEs are current.
+func (s *LGESet) Err() error {
+	return s.t.err
+}
+
+// Floor returns the largest LGE in an LGESet that is less than or equal to
+// a given LGE.  The second return value is false if there is no such LGE.
+func (s *LGESet) Floor(k LGE) (LGE, bool) {
+	if it := s.t.floor(k); it != nil {
+		return it.key, true
+	}
+	return 0, false
+}
+
+// Ceiling returns the smallest LGE in an LGESet that is greater than or
+// equal to a given LGE.  The second return value is false if there is no
+// such LGE.
+func (s *LGESet) Ceiling(k LGE) (LGE, bool) {
+	if it := s.t.ceiling(k); it != nil {
+		return it.key, true
+	}
+	return 0, false
+}
+
+// Range calls a function on each LGE in an LGESet from lo to hi inclusive,
+// in increasing order, until the function returns false.  The function must
+// not modify the set.
+func (s *LGESet) Range(lo, hi LGE, f func(k LGE) bool) {
+	s.t.ascend(lo, hi, func(it *lgeItem[struct{}]) bool {
+		return f(it.key)
+	})
+}
+
+// Each calls a function on each LGE in an LGESet, in increasing order,
+// until the function returns false.  The function must not modify the set.
+func (s *LGESet) Each(f func(k LGE) bool) {
+	s.Range(0, ^LGE(0), f)
+}
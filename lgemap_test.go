@@ -0,0 +1,242 @@
+// This file provides unit tests for LGEMap and LGESet.
+
+package intern_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// checkLGEMap compares an LGEMap to a map from strings to values.
+func checkLGEMap(t *testing.T, m *intern.LGEMap[int], ref map[string]int) {
+	// Iterate over the map first so it can re-key itself if necessary.
+	var strs []string
+	m.Each(func(k intern.LGE, v int) bool {
+		s := k.String()
+		if len(strs) > 0 && s <= strs[len(strs)-1] {
+			t.Fatalf("%q follows %q", s, strs[len(strs)-1])
+		}
+		if ref[s] != v {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, ref[s], v)
+		}
+		strs = append(strs, s)
+		return true
+	})
+	if len(strs) != len(ref) || m.Len() != len(ref) {
+		t.Fatalf("Expected %d LGEs but saw %d (%d visited)", len(ref), m.Len(), len(strs))
+	}
+
+	// Look up every string and its neighbors.
+	for i, s := range strs {
+		k, ok := intern.LookupLGE(s)
+		if !ok {
+			t.Fatalf("Failed to look up %q", s)
+		}
+		if v, ok := m.Get(k); !ok || v != ref[s] {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, ref[s], v)
+		}
+		if k2, _, ok := m.Floor(k + 1); !ok || k2 != k {
+			t.Fatalf("Incorrect floor of %q", s)
+		}
+		if k2, _, ok := m.Ceiling(k - 1); !ok || k2 != k {
+			t.Fatalf("Incorrect ceiling of %q", s)
+		}
+		if i+1 < len(strs) {
+			if k2, _, ok := m.Ceiling(k + 1); !ok || k2.String() != strs[i+1] {
+				t.Fatalf("Incorrect ceiling of %q + 1", s)
+			}
+		}
+		if i > 0 {
+			if k2, _, ok := m.Floor(k - 1); !ok || k2.String() != strs[i-1] {
+				t.Fatalf("Incorrect floor of %q - 1", s)
+			}
+		}
+	}
+	if len(strs) == 0 {
+		return
+	}
+	first, _ := intern.LookupLGE(strs[0])
+	last, _ := intern.LookupLGE(strs[len(strs)-1])
+	if _, _, ok := m.Floor(first - 1); ok {
+		t.Fatalf("Unexpectedly found a floor of %q - 1", strs[0])
+	}
+	if _, _, ok := m.Ceiling(last + 1); ok {
+		t.Fatalf("Unexpectedly found a ceiling of %q + 1", strs[len(strs)-1])
+	}
+
+	// Scan the middle half of the map.
+	lo, _ := intern.LookupLGE(strs[len(strs)/4])
+	hi, _ := intern.LookupLGE(strs[3*len(strs)/4])
+	var mid []string
+	m.Range(lo, hi, func(k intern.LGE, v int) bool {
+		mid = append(mid, k.String())
+		return true
+	})
+	if len(mid) != 3*len(strs)/4-len(strs)/4+1 || mid[0] != strs[len(strs)/4] {
+		t.Fatalf("Incorrect range of %d LGEs starting with %q", len(mid), mid[0])
+	}
+}
+
+// TestLGEMap compares LGEMap operations to the equivalent map operations,
+// including across a remapping of all LGEs.
+func TestLGEMap(t *testing.T) {
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	strs := generateRandomStrings(20000)
+	intern.PreLGEMulti(strs)
+	keys, err := intern.NewLGEMulti(strs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Randomly insert and delete strings.
+	prng := rand.New(rand.NewSource(6666)) // Constant for reproducibility
+	var m intern.LGEMap[int]
+	ref := make(map[string]int)
+	for i := 0; i < 100000; i++ {
+		j := prng.Intn(len(strs))
+		if prng.Intn(3) == 0 {
+			err = m.Delete(keys[j])
+			delete(ref, strs[j])
+		} else {
+			err = m.Set(keys[j], i)
+			ref[strs[j]] = i
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkLGEMap(t, &m, ref)
+
+	// Ensure that the map survives remapping.
+	if _, err = intern.RemapAllLGEs(); err != nil {
+		t.Fatal(err)
+	}
+	checkLGEMap(t, &m, ref)
+
+	// Ensure that the map survives forgetting all LGEs.
+	intern.ForgetAllLGEs()
+	checkLGEMap(t, &m, ref)
+
+	// Delete everything.
+	for s := range ref {
+		k, _ := intern.LookupLGE(s)
+		if err = m.Delete(k); err != nil {
+			t.Fatal(err)
+		}
+		delete(ref, s)
+	}
+	checkLGEMap(t, &m, ref)
+}
+
+// TestLGESet tests basic LGESet operations.
+func TestLGESet(t *testing.T) {
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	intern.PreLGEMulti(ozChars)
+	var set intern.LGESet
+	for _, s := range ozChars {
+		k, err := intern.NewLGE(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = set.Add(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if set.Len() != len(ozChars) {
+		t.Fatalf("Expected %d LGEs but saw %d", len(ozChars), set.Len())
+	}
+
+	// Remove every other character whose name begins with "Ki".
+	lo, hi, ok := intern.LGEPrefixRange("Ki")
+	if !ok {
+		t.Fatal("Failed to find any characters beginning with \"Ki\"")
+	}
+	var ki []string
+	set.Range(lo, hi, func(k intern.LGE) bool {
+		ki = append(ki, k.String())
+		return true
+	})
+	for i, s := range ki {
+		if i%2 == 1 {
+			k, _ := intern.LookupLGE(s)
+			if err := set.Remove(k); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Remap the LGEs then check what's left.
+	if _, err := intern.RemapAllLGEs(); err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for i, s := range ki {
+		k, _ := intern.LookupLGE(s)
+		if set.Contains(k) != (i%2 == 0) {
+			t.Fatalf("Incorrect presence of %q", s)
+		}
+		if i%2 == 0 {
+			kept = append(kept, s)
+		} else if k2, ok := set.Ceiling(k); !ok || k2.String() == s {
+			t.Fatalf("Incorrect ceiling of %q", s)
+		}
+	}
+	lo, hi, _ = intern.LGEPrefixRange("Ki")
+	var got []string
+	set.Range(lo, hi, func(k intern.LGE) bool {
+		got = append(got, k.String())
+		return true
+	})
+	if !sort.StringsAreSorted(got) || len(got) != len(kept) || got[0] != kept[0] {
+		t.Fatalf("Expected %v but saw %v", kept, got)
+	}
+	if set.Len() != len(ozChars)-len(ki)/2 {
+		t.Fatalf("Expected %d LGEs but saw %d", len(ozChars)-len(ki)/2, set.Len())
+	}
+}
+
+// TestLGEMapStale ensures that an LGEMap whose keys cannot be re-interned
+// reports an error rather than panicking and recovers once they can.
+func TestLGEMapStale(t *testing.T) {
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	var m intern.LGEMap[int]
+	ref := make(map[string]int)
+	for i, s := range ozChars[:20] {
+		k, err := intern.NewLGE(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = m.Set(k, i); err != nil {
+			t.Fatal(err)
+		}
+		ref[s] = i
+	}
+
+	// An empty, frozen table cannot accept the map's strings.
+	intern.ForgetAllLGEs()
+	if err := intern.FreezeLGEs(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Delete(1); err == nil {
+		t.Fatal("Unexpectedly modified a map with stale keys")
+	}
+	if _, _, ok := m.Floor(^intern.LGE(0)); ok || m.Err() == nil {
+		t.Fatal("Expected a map with stale keys to report an error")
+	}
+	if n := m.Len(); n != 0 {
+		t.Fatalf("Expected a map with stale keys to be empty but saw %d keys", n)
+	}
+
+	// Thawing the table lets the map re-key itself.
+	intern.ForgetAllLGEs()
+	checkLGEMap(t, &m, ref)
+	if m.Err() != nil {
+		t.Fatal(m.Err())
+	}
+}
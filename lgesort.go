@@ -0,0 +1,253 @@
+// This file provides sorting and set operations on slices of LGEs.
+
+package intern
+
+import "sort"
+
+// radixThreshold is the length below which SortLGEs uses a comparison sort
+// instead of a radix sort.
+const radixThreshold = 256
+
+// SortLGEs sorts a slice of LGEs into increasing order, which is also the
+// order of their strings.  Because LGEs are integers, SortLGEs can use a
+// radix sort, which is typically several times faster than sort.Sort.  The
+// radix sort skips each byte position in which all LGEs agree, which is
+// common for the high-order bytes of LGEs allocated from a small table.
+func SortLGEs(ls []LGE) {
+	if len(ls) < radixThreshold {
+		sort.Slice(ls, func(i, j int) bool { return ls[i] < ls[j] })
+		return
+	}
+	src, dst := ls, make([]LGE, len(ls))
+	for shift := uint(0); shift < 64; shift += 8 {
+		// Count the occurrences of each byte value.
+		var counts [256]int
+		for _, l := range src {
+			counts[byte(l>>shift)]++
+		}
+		if counts[byte(src[0]>>shift)] == len(src) {
+			continue // All LGEs share this byte.
+		}
+
+		// Distribute the LGEs by byte value.
+		pos := 0
+		for b, c := range counts {
+			counts[b] = pos
+			pos += c
+		}
+		for _, l := range src {
+			b := byte(l >> shift)
+			dst[counts[b]] = l
+			counts[b]++
+		}
+		src, dst = dst, src
+	}
+	if &src[0] != &ls[0] {
+		copy(ls, src)
+	}
+}
+
+// MergeSortedLGEs merges two sorted slices of LGEs into a new sorted slice.
+// LGEs that appear in both slices appear in the result as many times as they
+// appear in total.
+func MergeSortedLGEs(a, b []LGE) []LGE {
+	m := make([]LGE, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if b[j] < a[i] {
+			m = append(m, b[j])
+			j++
+		} else {
+			m = append(m, a[i])
+			i++
+		}
+	}
+	m = append(m, a[i:]...)
+	return append(m, b[j:]...)
+}
+
+// IntersectSortedLGEs returns a new sorted slice of the LGEs that appear in
+// both of two sorted slices.  An LGE that appears in both slices appears in
+// the result as many times as it appears in the slice in which it appears
+// less often.  When one slice is much shorter than the other,
+// IntersectSortedLGEs binary-searches the longer slice rather than scanning
+// it.
+func IntersectSortedLGEs(a, b []LGE) []LGE {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var m []LGE
+	if len(b) > 16*len(a) {
+		// Binary-search b for each element of a.
+		for _, l := range a {
+			j := sort.Search(len(b), func(j int) bool { return b[j] >= l })
+			if j == len(b) {
+				break
+			}
+			if b[j] == l {
+				m = append(m, l)
+				j++
+			}
+			b = b[j:]
+		}
+		return m
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			m = append(m, a[i])
+			i++
+			j++
+		}
+	}
+	return m
+}
+
+// DedupSortedLGEs removes adjacent duplicates from a sorted slice of LGEs in
+// place and returns the shortened slice.
+func DedupSortedLGEs(ls []LGE) []LGE {
+	if len(ls) == 0 {
+		return ls
+	}
+	n := 1
+	for _, l := range ls[1:] {
+		if l != ls[n-1] {
+			ls[n] = l
+			n++
+		}
+	}
+	return ls[:n]
+}
+
+// An LGEStream is a function that returns successive LGEs in nondecreasing
+// order.  The second return value is false once the stream is exhausted.
+type LGEStream func() (LGE, bool)
+
+// StreamLGEs returns an LGEStream that returns the LGEs in a sorted slice.
+func StreamLGEs(ls []LGE) LGEStream {
+	return func() (LGE, bool) {
+		if len(ls) == 0 {
+			return 0, false
+		}
+		l := ls[0]
+		ls = ls[1:]
+		return l, true
+	}
+}
+
+// An lgeJoinHead is the next LGE from one stream in an LGEJoin.
+type lgeJoinHead struct {
+	l LGE // Next LGE from the stream
+	i int // Index of the stream
+}
+
+// An LGEJoin performs a merge join of multiple sorted LGE streams.  It visits
+// each distinct LGE that appears in any stream, in increasing order, and
+// reports which streams contain it.  The following shows how to use an
+// LGEJoin to find the LGEs that appear in at least two of three streams:
+//
+//	j := intern.NewLGEJoin(s0, s1, s2)
+//	for j.Next() {
+//		if len(j.Streams()) >= 2 {
+//			fmt.Println(j.LGE())
+//		}
+//	}
+type LGEJoin struct {
+	streams []LGEStream   // Streams being joined
+	heads   []lgeJoinHead // Min-heap of each nonempty stream's next LGE
+	cur     LGE           // Current LGE
+	match   []int         // Indexes of the streams containing cur
+}
+
+// NewLGEJoin returns an LGEJoin over a list of sorted LGE streams.  Call
+// Next to advance to the first LGE.
+func NewLGEJoin(streams ...LGEStream) *LGEJoin {
+	j := &LGEJoin{streams: streams}
+	for i, s := range streams {
+		if l, ok := s(); ok {
+			j.heads = append(j.heads, lgeJoinHead{l: l, i: i})
+		}
+	}
+	for k := len(j.heads)/2 - 1; k >= 0; k-- {
+		j.down(k)
+	}
+	return j
+}
+
+// down restores the heap order of an LGEJoin's heads by moving the head at
+// position k toward the leaves.
+func (j *LGEJoin) down(k int) {
+	n := len(j.heads)
+	for {
+		c := 2*k + 1
+		if c >= n {
+			return
+		}
+		if c+1 < n && j.heads[c+1].l < j.heads[c].l {
+			c++
+		}
+		if j.heads[c].l >= j.heads[k].l {
+			return
+		}
+		j.heads[k], j.heads[c] = j.heads[c], j.heads[k]
+		k = c
+	}
+}
+
+// Next advances an LGEJoin to the next distinct LGE.  It returns false if
+// all streams are exhausted.
+func (j *LGEJoin) Next() bool {
+	if len(j.heads) == 0 {
+		return false
+	}
+	j.cur = j.heads[0].l
+	j.match = j.match[:0]
+	for len(j.heads) > 0 && j.heads[0].l == j.cur {
+		// Record the stream and advance it.
+		i := j.heads[0].i
+		if len(j.match) == 0 || j.match[len(j.match)-1] != i {
+			j.match = append(j.match, i)
+		}
+		if l, ok := j.streams[i](); ok {
+			j.heads[0].l = l
+		} else {
+			n := len(j.heads) - 1
+			j.heads[0] = j.heads[n]
+			j.heads = j.heads[:n]
+		}
+		j.down(0)
+	}
+	sort.Ints(j.match)
+	j.match = dedupInts(j.match)
+	return true
+}
+
+// dedupInts removes adjacent duplicates from a sorted slice of ints in place
+// and returns the shortened slice.
+func dedupInts(xs []int) []int {
+	n := 0
+	for _, x := range xs {
+		if n == 0 || x != xs[n-1] {
+			xs[n] = x
+			n++
+		}
+	}
+	return xs[:n]
+}
+
+// LGE returns an LGEJoin's current LGE.
+func (j *LGEJoin) LGE() LGE {
+	return j.cur
+}
+
+// Streams returns the indexes, in increasing order, of the streams that
+// contain an LGEJoin's current LGE.  The slice is valid only until the next
+// call to Next.
+func (j *LGEJoin) Streams() []int {
+	return j.match
+}
@@ -0,0 +1,161 @@
+// This file provides unit tests for sorting and set operations on LGE
+// slices.
+
+package intern_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// randomSortedLGEs returns a sorted slice of n LGEs drawn from [0, max),
+// possibly with duplicates.
+func randomSortedLGEs(prng *rand.Rand, n int, max int64) []intern.LGE {
+	ls := make([]intern.LGE, n)
+	for i := range ls {
+		ls[i] = intern.LGE(prng.Int63n(max))
+	}
+	sort.Sort(LGESlice(ls))
+	return ls
+}
+
+// lgeCounts returns the number of occurrences of each LGE in a slice.
+func lgeCounts(ls []intern.LGE) map[intern.LGE]int {
+	m := make(map[intern.LGE]int, len(ls))
+	for _, l := range ls {
+		m[l]++
+	}
+	return m
+}
+
+// expectSortedLGEs ensures that a slice of LGEs is sorted and contains a
+// given number of occurrences of each LGE.
+func expectSortedLGEs(t *testing.T, what string, ls []intern.LGE, counts map[intern.LGE]int) {
+	t.Helper()
+	if !sort.IsSorted(LGESlice(ls)) {
+		t.Fatalf("%s: result is not sorted", what)
+	}
+	n := 0
+	for l, c := range lgeCounts(ls) {
+		if counts[l] != c {
+			t.Fatalf("%s: expected %d occurrences of %d but saw %d", what, counts[l], l, c)
+		}
+		n += c
+	}
+	for _, c := range counts {
+		n -= c
+	}
+	if n != 0 {
+		t.Fatalf("%s: result has the wrong length", what)
+	}
+}
+
+// TestSortLGEs compares SortLGEs to sort.Sort.
+func TestSortLGEs(t *testing.T) {
+	prng := rand.New(rand.NewSource(6868)) // Constant for reproducibility
+	for _, n := range []int{0, 1, 100, 10000} {
+		for _, max := range []int64{1 << 62, 1 << 20, 10} {
+			ls := make([]intern.LGE, n)
+			for i := range ls {
+				ls[i] = intern.LGE(prng.Int63n(max)) << 1
+			}
+			ref := make([]intern.LGE, n)
+			copy(ref, ls)
+			sort.Sort(LGESlice(ref))
+			intern.SortLGEs(ls)
+			for i := range ls {
+				if ls[i] != ref[i] {
+					t.Fatalf("Expected %d at position %d but saw %d", ref[i], i, ls[i])
+				}
+			}
+		}
+	}
+}
+
+// TestSortedLGEOps tests merging, intersecting, and deduplicating sorted
+// slices of LGEs.
+func TestSortedLGEOps(t *testing.T) {
+	prng := rand.New(rand.NewSource(6869)) // Constant for reproducibility
+	for _, sizes := range [][2]int{{0, 0}, {0, 10}, {1000, 1000}, {10, 5000}} {
+		a := randomSortedLGEs(prng, sizes[0], 2000)
+		b := randomSortedLGEs(prng, sizes[1], 2000)
+		ac, bc := lgeCounts(a), lgeCounts(b)
+
+		// Merge the two slices.
+		union := make(map[intern.LGE]int)
+		for l, c := range ac {
+			union[l] += c
+		}
+		for l, c := range bc {
+			union[l] += c
+		}
+		expectSortedLGEs(t, "MergeSortedLGEs", intern.MergeSortedLGEs(a, b), union)
+
+		// Intersect the two slices.
+		inter := make(map[intern.LGE]int)
+		for l, c := range ac {
+			if c2, ok := bc[l]; ok {
+				inter[l] = min(c, c2)
+			}
+		}
+		expectSortedLGEs(t, "IntersectSortedLGEs", intern.IntersectSortedLGEs(a, b), inter)
+		expectSortedLGEs(t, "IntersectSortedLGEs", intern.IntersectSortedLGEs(b, a), inter)
+
+		// Deduplicate the first slice.
+		dedup := make(map[intern.LGE]int)
+		for l := range ac {
+			dedup[l] = 1
+		}
+		expectSortedLGEs(t, "DedupSortedLGEs", intern.DedupSortedLGEs(a), dedup)
+	}
+}
+
+// TestLGEJoin compares the results of an LGEJoin to the contents of the
+// streams being joined.
+func TestLGEJoin(t *testing.T) {
+	prng := rand.New(rand.NewSource(6870)) // Constant for reproducibility
+	lists := [][]intern.LGE{
+		randomSortedLGEs(prng, 500, 1000),
+		nil,
+		randomSortedLGEs(prng, 1000, 1000),
+		randomSortedLGEs(prng, 50, 1000),
+	}
+	counts := make([]map[intern.LGE]int, len(lists))
+	all := make(map[intern.LGE]int)
+	streams := make([]intern.LGEStream, len(lists))
+	for i, ls := range lists {
+		counts[i] = lgeCounts(ls)
+		for _, l := range ls {
+			all[l] = 1
+		}
+		streams[i] = intern.StreamLGEs(ls)
+	}
+	j := intern.NewLGEJoin(streams...)
+	var seen []intern.LGE
+	for j.Next() {
+		l := j.LGE()
+		var expect []int
+		for i, c := range counts {
+			if c[l] > 0 {
+				expect = append(expect, i)
+			}
+		}
+		got := j.Streams()
+		if len(got) != len(expect) {
+			t.Fatalf("Expected LGE %d to appear in streams %v but saw %v", l, expect, got)
+		}
+		for i := range got {
+			if got[i] != expect[i] {
+				t.Fatalf("Expected LGE %d to appear in streams %v but saw %v", l, expect, got)
+			}
+		}
+		seen = append(seen, l)
+	}
+	expectSortedLGEs(t, "LGEJoin", seen, all)
+	if j.Next() {
+		t.Fatal("Next succeeded after all streams were exhausted")
+	}
+}
@@ -0,0 +1,200 @@
+// This file provides a minimal perfect hash function for use by frozen
+// symbol tables.
+
+package intern
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// mphLoad is the average number of keys per bucket in a minimal perfect hash.
+const mphLoad = 4
+
+// mphDirect marks a displacement that directly encodes a slot number.  It is
+// used for buckets that contain a single key.
+const mphDirect = 1 << 31
+
+// mphMaxTries is the number of displacements to try for each bucket before
+// giving up and starting over with a different seed.
+const mphMaxTries = 1 << 20
+
+// An mph is a minimal perfect hash table mapping strings to symbols.  It
+// uses the "compress, hash, and displace" (CHD) algorithm: Each key is first
+// hashed into one of a small number of buckets.  Each bucket is assigned a
+// displacement that, combined with a second hash of each key in the bucket,
+// sends every key to a distinct slot.  A lookup therefore costs one hash of
+// the key and one string comparison to verify that the key is present.
+type mph struct {
+	seed uint64   // Seed for the hash function
+	disp []uint32 // Displacement of each bucket
+	keys []string // Keys in slot order
+	syms []symbol // Symbols in slot order
+}
+
+// mix scrambles the bits of a 64-bit integer (the SplitMix64 finalizer).
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// hashString hashes a string with a given seed.  It consumes the string eight
+// bytes at a time then applies a final mixing step.  Unlike hash/maphash, the
+// result is the same in every process, which lets a hash table be written to
+// a file and read back.
+func hashString(s string, seed uint64) uint64 {
+	const k1, k2 = 0x87c37b91114253d5, 0x4cf5ad432745937f
+	h := mix(seed) ^ uint64(len(s))*k2
+	for ; len(s) >= 8; s = s[8:] {
+		v := uint64(s[0]) | uint64(s[1])<<8 | uint64(s[2])<<16 | uint64(s[3])<<24 |
+			uint64(s[4])<<32 | uint64(s[5])<<40 | uint64(s[6])<<48 | uint64(s[7])<<56
+		h = bits.RotateLeft64(h^(v*k1), 31) * k2
+	}
+	var v uint64
+	for i := len(s) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(s[i])
+	}
+	return mix(h ^ v*k1)
+}
+
+// reduce maps a 64-bit hash value uniformly onto the range [0, n) without
+// performing a division.
+func reduce(h uint64, n int) int {
+	hi, _ := bits.Mul64(h, uint64(n))
+	return int(hi)
+}
+
+// slot returns the slot in which a key with a given hash resides given its
+// bucket's displacement.
+func (m *mph) slot(h uint64, d uint32) int {
+	if d&mphDirect != 0 {
+		return int(d &^ mphDirect)
+	}
+	h2 := mix(h ^ 0x9e3779b97f4a7c15)
+	h3 := mix(h2) | 1
+	return reduce(h2+uint64(d)*h3, len(m.keys))
+}
+
+// lookup returns the symbol associated with a string.
+func (m *mph) lookup(s string) (symbol, bool) {
+	if len(m.keys) == 0 {
+		return 0, false
+	}
+	h := hashString(s, m.seed)
+	i := m.slot(h, m.disp[reduce(h, len(m.disp))])
+	if m.keys[i] != s {
+		return 0, false
+	}
+	return m.syms[i], true
+}
+
+// newMPH constructs a minimal perfect hash from a map of strings to symbols.
+func newMPH(strToSym map[string]symbol) *mph {
+	// Extract the keys.  Sorting them makes construction deterministic.
+	keys := make([]string, 0, len(strToSym))
+	for k := range strToSym {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Try successive seeds until construction succeeds.  A failure
+	// requires two keys to have the same 64-bit hash value, so more than
+	// one retry is almost unheard of.
+	for seed := uint64(0); ; seed++ {
+		m, ok := buildMPH(keys, seed)
+		if ok {
+			for i, k := range m.keys {
+				m.syms[i] = strToSym[k]
+			}
+			return m
+		}
+	}
+}
+
+// buildMPH attempts to construct a minimal perfect hash for a list of keys
+// using a given seed.  It returns the new mph, with symbols not yet filled
+// in, and a success code.
+func buildMPH(keys []string, seed uint64) (*mph, bool) {
+	// Assign each key to a bucket.
+	n := len(keys)
+	nb := (n + mphLoad - 1) / mphLoad
+	if nb == 0 {
+		nb = 1
+	}
+	m := &mph{
+		seed: seed,
+		disp: make([]uint32, nb),
+		keys: make([]string, n),
+		syms: make([]symbol, n),
+	}
+	hashes := make([]uint64, n)
+	buckets := make([][]int, nb)
+	for i, k := range keys {
+		hashes[i] = hashString(k, seed)
+		b := reduce(hashes[i], nb)
+		buckets[b] = append(buckets[b], i)
+	}
+
+	// Place the largest buckets first, while there are still plenty of
+	// free slots.
+	order := make([]int, nb)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(buckets[order[i]]) > len(buckets[order[j]])
+	})
+	used := make([]bool, n)
+	slots := make([]int, 0, mphLoad*2)
+	free := 0 // Lowest possibly free slot
+	for _, b := range order {
+		bkt := buckets[b]
+		switch len(bkt) {
+		case 0:
+			continue
+		case 1:
+			// Place a lone key in any free slot.
+			for used[free] {
+				free++
+			}
+			m.disp[b] = mphDirect | uint32(free)
+			used[free] = true
+			m.keys[free] = keys[bkt[0]]
+			continue
+		}
+
+		// Search for a displacement that sends every key in the
+		// bucket to a distinct, free slot.
+		var d uint32
+	Search:
+		for d = 0; d < mphMaxTries; d++ {
+			slots = slots[:0]
+			for _, k := range bkt {
+				s := m.slot(hashes[k], d)
+				if used[s] {
+					continue Search
+				}
+				for _, s2 := range slots {
+					if s == s2 {
+						continue Search
+					}
+				}
+				slots = append(slots, s)
+			}
+			break
+		}
+		if d == mphMaxTries {
+			return nil, false
+		}
+		m.disp[b] = d
+		for i, s := range slots {
+			used[s] = true
+			m.keys[s] = keys[bkt[i]]
+		}
+	}
+	return m, true
+}
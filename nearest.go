@@ -0,0 +1,159 @@
+// This file provides approximate matching of interned Eq strings by edit
+// distance.
+
+package intern
+
+import "sort"
+
+// levenshtein returns the number of single-character insertions, deletions,
+// and substitutions needed to turn one string into another.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			d := prev[j-1]
+			if ra[i-1] != rb[j-1] {
+				d++
+			}
+			if prev[j]+1 < d {
+				d = prev[j] + 1
+			}
+			if cur[j-1]+1 < d {
+				d = cur[j-1] + 1
+			}
+			cur[j] = d
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// A bkNode is a node in a bkTree.  Every string in the subtree rooted at
+// children[d] is at distance d from str.
+type bkNode struct {
+	str      string          // String at this node
+	sym      symbol          // Symbol associated with str
+	children map[int]*bkNode // Children, keyed by distance from str
+}
+
+// A bkTree (Burkhard-Keller tree) indexes strings by edit distance.  Because
+// edit distance obeys the triangle inequality, a search for strings within
+// distance k of s need visit only those children of each node n whose key
+// lies within k of the distance from s to n.str.
+type bkTree struct {
+	root *bkNode
+}
+
+// insert adds a string and its symbol to a bkTree.
+func (bk *bkTree) insert(s string, sym symbol) {
+	nn := &bkNode{str: s, sym: sym}
+	if bk.root == nil {
+		bk.root = nn
+		return
+	}
+	for n := bk.root; ; {
+		d := levenshtein(s, n.str)
+		if d == 0 {
+			return
+		}
+		c, ok := n.children[d]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[int]*bkNode)
+			}
+			n.children[d] = nn
+			return
+		}
+		n = c
+	}
+}
+
+// search calls a function on each string within a given distance of s,
+// along with its symbol and distance.
+func (bk *bkTree) search(s string, maxDist int, f func(str string, sym symbol, d int)) {
+	if bk.root == nil {
+		return
+	}
+	stack := []*bkNode{bk.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		d := levenshtein(s, n.str)
+		if d <= maxDist {
+			f(n.str, n.sym, d)
+		}
+		for k, c := range n.children {
+			if k >= d-maxDist && k <= d+maxDist {
+				stack = append(stack, c)
+			}
+		}
+	}
+}
+
+// buildNearIndex returns a state's bkTree, building one if necessary.  Once
+// built, the tree is maintained by setSym.  The caller must hold the state's
+// write lock.
+func (st *state) buildNearIndex() *bkTree {
+	if st.near != nil {
+		return st.near
+	}
+	st.near = &bkTree{}
+	st.eachSym(st.near.insert)
+	return st.near
+}
+
+// NearestEqs returns the Eqs of up to limit interned strings whose edit
+// (Levenshtein) distance from s, measured in characters, is at most maxDist.
+// The results are sorted by increasing distance, then by string.  A limit of
+// zero or less imposes no limit.  This is intended for "did you mean"
+// suggestions for mistyped identifiers.
+//
+// The first call to NearestEqs builds a BK-tree of all interned strings,
+// which NewEq then keeps up to date.  ForgetAllEqs discards the tree.  The
+// smaller maxDist is, the fewer strings NearestEqs needs to examine.
+func NearestEqs(s string, maxDist int, limit int) []Eq {
+	type result struct {
+		str  string
+		sym  symbol
+		dist int
+	}
+	var rs []result
+	collect := func(str string, sym symbol, d int) {
+		rs = append(rs, result{str: str, sym: sym, dist: d})
+	}
+	if maxDist < 0 {
+		return nil
+	}
+	eq.RLock()
+	if eq.near != nil {
+		eq.near.search(s, maxDist, collect)
+		eq.RUnlock()
+	} else {
+		eq.RUnlock()
+		eq.Lock()
+		eq.buildNearIndex().search(s, maxDist, collect)
+		eq.Unlock()
+	}
+
+	// Sort and truncate the results.
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].dist != rs[j].dist {
+			return rs[i].dist < rs[j].dist
+		}
+		return rs[i].str < rs[j].str
+	})
+	if limit > 0 && len(rs) > limit {
+		rs = rs[:limit]
+	}
+	eqs := make([]Eq, len(rs))
+	for i, r := range rs {
+		eqs[i] = Eq(r.sym)
+	}
+	return eqs
+}
@@ -0,0 +1,45 @@
+// This file provides unit tests for approximate matching of interned
+// strings.
+
+package intern_test
+
+import (
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// TestNearestEqs ensures that NearestEqs suggests the expected strings.
+func TestNearestEqs(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	intern.NewEqMulti(ozChars)
+	for _, tc := range []struct {
+		str     string
+		maxDist int
+		limit   int
+		want    []string
+	}{
+		{"Dorthy Gale", 1, 0, []string{"Dorothy Gale"}},
+		{"Kink Kynd", 2, 0, []string{"King Kynd"}},
+		{"Mr. Yoop", 1, 0, []string{"Mr. Yoop", "Mrs. Yoop"}},
+		{"Mr. Yoop", 1, 1, []string{"Mr. Yoop"}},
+		{"Zebra", 2, 0, nil},
+		{"Ojo", -1, 0, nil},
+	} {
+		got := intern.NearestEqs(tc.str, tc.maxDist, tc.limit)
+		expectEqs(t, tc.str, got, tc.want)
+	}
+
+	// Ensure that new Eqs are found and that multi-byte characters count
+	// as single characters.
+	intern.NewEq("Tik-Tok")
+	intern.NewEq("Tïk-Tök")
+	expectEqs(t, "Tik-Tok", intern.NearestEqs("Tik-Tok", 2, 0), []string{"Tik-Tok", "Tïk-Tök"})
+
+	// Ensure that forgetting all Eqs discards the index.
+	intern.ForgetAllEqs()
+	if eqs := intern.NearestEqs("Tik-Tok", 2, 0); len(eqs) != 0 {
+		t.Fatalf("Expected no matches but saw %d", len(eqs))
+	}
+}
@@ -0,0 +1,88 @@
+// This file provides longest-prefix and completion queries over the set of
+// interned Eq strings.
+
+package intern
+
+// A prefixIndex is an index that supports prefix queries.  DATrie is the
+// canonical example.
+type prefixIndex interface {
+	LongestPrefix(s string) (Eq, int, bool)
+	EachPrefix(prefix string, f func(s string, e Eq) bool)
+}
+
+// prefixIndex returns an index that supports prefix queries over a state's
+// strings or nil if no such index has been built yet.  The caller must hold
+// the state's lock.
+func (st *state) prefixIndex() prefixIndex {
+	if st.prefixTrie != nil {
+		return st.prefixTrie
+	}
+	if pi, ok := st.strIndex.(prefixIndex); ok && st.frozenTable() == nil {
+		return pi
+	}
+	return nil
+}
+
+// buildPrefixIndex returns an index that supports prefix queries over a
+// state's strings, building one if necessary.  Once built, the index is
+// maintained by setSym.  The caller must hold the state's write lock.
+func (st *state) buildPrefixIndex() prefixIndex {
+	if pi := st.prefixIndex(); pi != nil {
+		return pi
+	}
+	st.prefixTrie = &DATrie{}
+	st.eachSym(func(s string, sym symbol) {
+		st.prefixTrie.Insert(s, Eq(sym))
+	})
+	return st.prefixTrie
+}
+
+// withPrefixIndex calls a function on an index that supports prefix queries
+// over a state's strings while holding the state's lock.
+func (st *state) withPrefixIndex(f func(pi prefixIndex)) {
+	st.RLock()
+	if pi := st.prefixIndex(); pi != nil {
+		f(pi)
+		st.RUnlock()
+		return
+	}
+	st.RUnlock()
+	st.Lock()
+	defer st.Unlock()
+	f(st.buildPrefixIndex())
+}
+
+// LongestPrefixEq returns the Eq of the longest interned string that is a
+// prefix of a given string, along with the length of that prefix in bytes.
+// This is useful for routing, where s is a path and the interned strings are
+// route names.  The third return value is false if no interned string is a
+// prefix of s.
+//
+// The first call to LongestPrefixEq or CompleteEqs builds a trie of all
+// interned strings, which NewEq then keeps up to date.  ForgetAllEqs
+// discards the trie.  Programs that make heavy use of prefix queries can
+// avoid the cost of the extra trie by installing a DATrie with SetEqIndex.
+func LongestPrefixEq(s string) (Eq, int, bool) {
+	var e Eq
+	var n int
+	var ok bool
+	eq.withPrefixIndex(func(pi prefixIndex) {
+		e, n, ok = pi.LongestPrefix(s)
+	})
+	return e, n, ok
+}
+
+// CompleteEqs returns the Eqs of up to limit interned strings that begin with
+// a given prefix, in order of their strings.  This is useful for command-line
+// completion.  A limit of zero or less imposes no limit.  See LongestPrefixEq
+// for a discussion of performance.
+func CompleteEqs(prefix string, limit int) []Eq {
+	var eqs []Eq
+	eq.withPrefixIndex(func(pi prefixIndex) {
+		pi.EachPrefix(prefix, func(s string, e Eq) bool {
+			eqs = append(eqs, e)
+			return limit <= 0 || len(eqs) < limit
+		})
+	})
+	return eqs
+}
@@ -0,0 +1,91 @@
+// This file provides unit tests for prefix queries over interned strings.
+
+package intern_test
+
+import (
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// checkPrefixQueries ensures that LongestPrefixEq and CompleteEqs return the
+// expected results for a table of route names.
+func checkPrefixQueries(t *testing.T, routes map[string]intern.Eq) {
+	t.Helper()
+	for _, tc := range []struct {
+		path  string
+		route string
+	}{
+		{"/users/42/posts", "/users/"},
+		{"/users", "/users"},
+		{"/user", "/"},
+		{"/posts/7", "/posts/"},
+		{"/", "/"},
+	} {
+		e, n, ok := intern.LongestPrefixEq(tc.path)
+		if !ok || e != routes[tc.route] || n != len(tc.route) {
+			t.Fatalf("Expected %q to match route %q but saw %q", tc.path, tc.route, tc.path[:n])
+		}
+	}
+	if _, _, ok := intern.LongestPrefixEq("users"); ok {
+		t.Fatal("Unexpectedly matched a route for \"users\"")
+	}
+	for _, tc := range []struct {
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{"/u", 0, []string{"/users", "/users/", "/users/admin"}},
+		{"/u", 2, []string{"/users", "/users/"}},
+		{"/p", 0, []string{"/posts/"}},
+		{"/x", 0, nil},
+	} {
+		got := intern.CompleteEqs(tc.prefix, tc.limit)
+		if len(got) != len(tc.want) {
+			t.Fatalf("Expected %d completions of %q but saw %d", len(tc.want), tc.prefix, len(got))
+		}
+		for i, e := range got {
+			if e != routes[tc.want[i]] {
+				t.Fatalf("Expected completion %d of %q to be %q but saw %q", i, tc.prefix, tc.want[i], e)
+			}
+		}
+	}
+}
+
+// TestPrefixQueries tests LongestPrefixEq and CompleteEqs as Eqs are added
+// and with every kind of index.
+func TestPrefixQueries(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.SetEqIndex(nil)
+	defer intern.ForgetAllEqs()
+	routes := make(map[string]intern.Eq)
+	for _, r := range []string{"/", "/users", "/users/"} {
+		routes[r] = intern.NewEq(r)
+	}
+	if _, _, ok := intern.LongestPrefixEq("/posts/7"); !ok {
+		t.Fatal("Failed to match route \"/\"")
+	}
+
+	// Add routes after the trie was built.
+	for _, r := range []string{"/users/admin", "/posts/"} {
+		routes[r] = intern.NewEq(r)
+	}
+	checkPrefixQueries(t, routes)
+
+	// Repeat the tests with a DATrie index and with a frozen table.
+	if err := intern.SetEqIndex(&intern.DATrie{}); err != nil {
+		t.Fatal(err)
+	}
+	checkPrefixQueries(t, routes)
+	intern.FreezeEqs()
+	checkPrefixQueries(t, routes)
+
+	// Ensure that forgetting all Eqs discards all routes.
+	intern.ForgetAllEqs()
+	if _, _, ok := intern.LongestPrefixEq("/users"); ok {
+		t.Fatal("Matched a route after ForgetAllEqs")
+	}
+	if eqs := intern.CompleteEqs("", 0); len(eqs) != 0 {
+		t.Fatalf("Expected no completions but saw %d", len(eqs))
+	}
+}
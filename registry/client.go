@@ -0,0 +1,271 @@
+// This file provides the registry client, which caches the server's Eqs.
+
+package registry
+
+import (
+	"bufio"
+	"net"
+	"sync"
+
+	"github.com/spakin/intern"
+)
+
+// A Table interns strings using a registry Server and caches the results.
+// Its NewEq, NewEqMulti, and String methods behave like those of
+// intern.EqTable; Intern and Strings are the same operations but return
+// rather than panic on errors, such as a lost connection to the server.  The
+// cache grows with every string the Table learns and is never evicted.
+// Because a Table's Eqs come from the server's table, they must be converted
+// back to strings with the Table's String method (or by another Table
+// connected to the same server) rather than with intern.Eq.String.  A Table
+// is safe for concurrent use.
+type Table struct {
+	conn net.Conn      // Connection to the server
+	r    *bufio.Reader // Buffered reader of conn
+	ioMu sync.Mutex    // Mutex serializing requests on conn
+
+	mu       sync.RWMutex         // Mutex protecting the fields below
+	strToEq  map[string]intern.Eq // Cached mapping from strings to Eqs
+	eqToStr  map[intern.Eq]string // Cached mapping from Eqs to strings
+	next     *batch               // Misses awaiting the next request or nil
+	flushing bool                 // Whether a goroutine is sending batches
+}
+
+// A batch is a set of strings that are interned with a single request.
+type batch struct {
+	strs []string      // Strings to intern
+	err  error         // Result of the request
+	done chan struct{} // Channel closed when the request completes
+}
+
+// Dial connects to a registry server at a given address and returns a Table
+// that uses it.  The network is typically "unix" or "tcp" (see net.Dial).
+func Dial(network, address string) (*Table, error) {
+	c, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewTable(c), nil
+}
+
+// NewTable returns a Table that uses an existing connection to a registry
+// server.
+func NewTable(c net.Conn) *Table {
+	return &Table{
+		conn:    c,
+		r:       bufio.NewReader(c),
+		strToEq: make(map[string]intern.Eq),
+		eqToStr: make(map[intern.Eq]string),
+	}
+}
+
+// Close closes a Table's connection to the server.
+func (t *Table) Close() error {
+	return t.conn.Close()
+}
+
+// roundTrip sends a request to the server and returns the body of the
+// response.  After an I/O error, it closes the connection, whose requests and
+// responses may no longer correspond.
+func (t *Table) roundTrip(req []byte) (*decoder, error) {
+	t.ioMu.Lock()
+	defer t.ioMu.Unlock()
+	if err := writeFrame(t.conn, req); err != nil {
+		t.conn.Close()
+		return nil, err
+	}
+	resp, err := readFrame(t.r)
+	if err != nil {
+		t.conn.Close()
+		return nil, err
+	}
+	if len(resp) == 0 {
+		return nil, errMalformed
+	}
+	if resp[0] != statusOK {
+		return nil, &ServerError{Msg: string(resp[1:])}
+	}
+	return &decoder{buf: resp[1:]}, nil
+}
+
+// cache records a set of strings and their Eqs.
+func (t *Table) cache(strs []string, eqs []intern.Eq) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, s := range strs {
+		t.strToEq[s] = eqs[i]
+		t.eqToStr[eqs[i]] = s
+	}
+}
+
+// fetch interns a set of strings that are missing from the cache.  If a
+// request is already in flight, fetch adds the strings to the next batch;
+// otherwise, it sends batches until none remain.
+func (t *Table) fetch(strs []string) error {
+	t.mu.Lock()
+	if t.next == nil {
+		t.next = &batch{done: make(chan struct{})}
+	}
+	b := t.next
+	b.strs = append(b.strs, strs...)
+	lead := !t.flushing
+	t.flushing = true
+	t.mu.Unlock()
+	if lead {
+		t.flush()
+	}
+	<-b.done
+	return b.err
+}
+
+// flush sends batches of strings to the server until no batch is waiting.
+func (t *Table) flush() {
+	for {
+		t.mu.Lock()
+		b := t.next
+		t.next = nil
+		if b == nil {
+			t.flushing = false
+			t.mu.Unlock()
+			return
+		}
+		t.mu.Unlock()
+		b.err = t.send(b.strs)
+		close(b.done)
+	}
+}
+
+// send interns a batch of strings with a single request and caches the
+// results.
+func (t *Table) send(strs []string) error {
+	// Remove duplicates and strings cached since the batch began.
+	seen := make(map[string]struct{}, len(strs))
+	uniq := strs[:0:0]
+	t.mu.RLock()
+	for _, s := range strs {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		if _, ok := t.strToEq[s]; !ok {
+			uniq = append(uniq, s)
+		}
+	}
+	t.mu.RUnlock()
+	if len(uniq) == 0 {
+		return nil
+	}
+
+	// Ask the server for the strings' Eqs.
+	d, err := t.roundTrip(appendStrings([]byte{opIntern}, uniq))
+	if err != nil {
+		return err
+	}
+	eqs := uints[intern.Eq](d)
+	if err = d.done(); err != nil {
+		return err
+	}
+	if len(eqs) != len(uniq) {
+		return errMalformed
+	}
+	t.cache(uniq, eqs)
+	return nil
+}
+
+// Intern maps each of a slice of strings to an Eq, asking the server only for
+// strings that are not already cached.  Concurrent calls' misses are
+// combined into as few requests as possible.
+func (t *Table) Intern(ss []string) ([]intern.Eq, error) {
+	eqs := make([]intern.Eq, len(ss))
+	var miss []string
+	t.mu.RLock()
+	for i, s := range ss {
+		e, ok := t.strToEq[s]
+		if !ok {
+			miss = append(miss, s)
+		}
+		eqs[i] = e
+	}
+	t.mu.RUnlock()
+	if len(miss) == 0 {
+		return eqs, nil
+	}
+	if err := t.fetch(miss); err != nil {
+		return nil, err
+	}
+	t.mu.RLock()
+	for i, s := range ss {
+		eqs[i] = t.strToEq[s]
+	}
+	t.mu.RUnlock()
+	return eqs, nil
+}
+
+// Strings maps each of a slice of Eqs back to a string, asking the server
+// only for Eqs that are not already cached.  It returns a ServerError if any
+// Eq is unknown to the server.
+func (t *Table) Strings(eqs []intern.Eq) ([]string, error) {
+	strs := make([]string, len(eqs))
+	var miss []intern.Eq
+	t.mu.RLock()
+	for i, e := range eqs {
+		s, ok := t.eqToStr[e]
+		if !ok {
+			miss = append(miss, e)
+		}
+		strs[i] = s
+	}
+	t.mu.RUnlock()
+	if len(miss) == 0 {
+		return strs, nil
+	}
+	d, err := t.roundTrip(appendUints([]byte{opString}, miss))
+	if err != nil {
+		return nil, err
+	}
+	found := d.strings()
+	if err = d.done(); err != nil {
+		return nil, err
+	}
+	if len(found) != len(miss) {
+		return nil, errMalformed
+	}
+	t.cache(found, miss)
+	t.mu.RLock()
+	for i, e := range eqs {
+		strs[i] = t.eqToStr[e]
+	}
+	t.mu.RUnlock()
+	return strs, nil
+}
+
+// NewEq maps a string to an Eq.  It panics if the server cannot be reached or
+// rejects the request.
+func (t *Table) NewEq(s string) intern.Eq {
+	eqs, err := t.Intern([]string{s})
+	if err != nil {
+		panic(err)
+	}
+	return eqs[0]
+}
+
+// NewEqMulti performs the same operation as NewEq but accepts a slice of
+// strings instead of an individual string.  All strings that are not already
+// cached are sent to the server in a single request.
+func (t *Table) NewEqMulti(ss []string) []intern.Eq {
+	eqs, err := t.Intern(ss)
+	if err != nil {
+		panic(err)
+	}
+	return eqs
+}
+
+// String converts an Eq back to a string.  It panics if the server cannot be
+// reached or does not recognize the Eq.
+func (t *Table) String(e intern.Eq) string {
+	strs, err := t.Strings([]intern.Eq{e})
+	if err != nil {
+		panic(err)
+	}
+	return strs[0]
+}
@@ -0,0 +1,151 @@
+// This file provides unit tests for the registry client.
+
+package registry_test
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/spakin/intern"
+	"github.com/spakin/intern/registry"
+)
+
+// eqTable is the subset of intern.EqTable's methods that a registry Table
+// also provides.
+type eqTable interface {
+	NewEq(s string) intern.Eq
+	NewEqMulti(ss []string) []intern.Eq
+	String(e intern.Eq) string
+}
+
+var _ eqTable = (*intern.EqTable)(nil)
+var _ eqTable = (*registry.Table)(nil)
+
+// startServer starts a registry server on a Unix-domain socket and returns
+// the server's table and the socket's address.  The server is closed when the
+// test ends.
+func startServer(t *testing.T) (*intern.EqTable, string) {
+	t.Helper()
+	addr := filepath.Join(t.TempDir(), "registry.sock")
+	l, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Skipf("Unix-domain sockets are unavailable: %v", err)
+	}
+	table := intern.NewEqTable()
+	srv := registry.NewServer(table)
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(l) }()
+	t.Cleanup(func() {
+		srv.Close()
+		if err := <-done; err != registry.ErrServerClosed {
+			t.Errorf("Expected ErrServerClosed but saw %v", err)
+		}
+	})
+	return table, addr
+}
+
+// dial connects a new Table to a server and closes it when the test ends.
+func dial(t *testing.T, network, addr string) *registry.Table {
+	t.Helper()
+	tbl, err := registry.Dial(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tbl.Close() })
+	return tbl
+}
+
+// TestTableShared ensures that two clients of the same server agree on every
+// Eq and that both agree with the server's table.
+func TestTableShared(t *testing.T) {
+	table, addr := startServer(t)
+	c1, c2 := dial(t, "unix", addr), dial(t, "unix", addr)
+	strs := make([]string, 300)
+	for i := range strs {
+		strs[i] = fmt.Sprintf("string %d", i%250)
+	}
+	eqs1 := c1.NewEqMulti(strs[:200])
+	for i, s := range strs {
+		e2 := c2.NewEq(s)
+		if i < 200 && e2 != eqs1[i] {
+			t.Fatalf("Clients disagree on %q: %d vs. %d", s, eqs1[i], e2)
+		}
+		if e, ok := table.LookupEq(s); !ok || e != e2 {
+			t.Fatalf("Client and server disagree on %q", s)
+		}
+		if s1 := c1.String(e2); s1 != s {
+			t.Fatalf("Expected %q but saw %q", s, s1)
+		}
+	}
+	if n := table.Len(); n != 250 {
+		t.Fatalf("Expected 250 strings but saw %d", n)
+	}
+
+	// Unknown Eqs are rejected.
+	if _, err := c2.Strings([]intern.Eq{12345}); err == nil {
+		t.Fatal("Unexpectedly converted an unknown Eq to a string")
+	}
+	if s := c2.String(eqs1[0]); s != strs[0] {
+		t.Fatalf("Expected %q but saw %q after an error", strs[0], s)
+	}
+}
+
+// TestTableConcurrent interns overlapping strings from many goroutines
+// sharing a single client.
+func TestTableConcurrent(t *testing.T) {
+	table, addr := startServer(t)
+	c := dial(t, "unix", addr)
+	const workers = 16
+	results := make([][]intern.Eq, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			res := make([]intern.Eq, 100)
+			for i := range res {
+				eqs, err := c.Intern([]string{fmt.Sprintf("word %d", (i+w)%100)})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				res[(i+w)%100] = eqs[0]
+			}
+			results[w] = res
+		}(w)
+	}
+	wg.Wait()
+	for w := 1; w < workers; w++ {
+		for i, e := range results[w] {
+			if e != results[0][i] {
+				t.Fatalf("Goroutines disagree on word %d", i)
+			}
+		}
+	}
+	if n := table.Len(); n != 100 {
+		t.Fatalf("Expected 100 strings but saw %d", n)
+	}
+}
+
+// TestTableTCP ensures that a client can reach a server over TCP and that
+// its cache keeps working after the server goes away.
+func TestTableTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("TCP sockets are unavailable: %v", err)
+	}
+	srv := registry.NewServer(intern.NewEqTable())
+	go srv.Serve(l)
+	c := dial(t, "tcp", l.Addr().String())
+	e := c.NewEq("Tik-Tok")
+	srv.Close()
+	if c.NewEq("Tik-Tok") != e || c.String(e) != "Tik-Tok" {
+		t.Fatal("Cached lookups failed after the server closed")
+	}
+	if _, err = c.Intern([]string{"Tik-Tok", "Jack Pumpkinhead"}); err == nil {
+		t.Fatal("Unexpectedly interned a string after the server closed")
+	}
+}
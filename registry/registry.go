@@ -0,0 +1,185 @@
+// Package registry shares a single Eq namespace among multiple processes on
+// one host.
+//
+// A Server owns the authoritative intern.EqTable and listens on a Unix-domain
+// or TCP socket.  Each client process connects with Dial and obtains a Table,
+// which provides the same NewEq, NewEqMulti, and String methods as
+// intern.EqTable.  A Table caches every string and Eq it learns from the
+// server, so repeated lookups never leave the process, and it combines
+// concurrent misses into a single request to the server.  Because every
+// client's Eqs come from the same server table, Eqs can be passed between
+// processes and compared directly.
+//
+// The protocol is deliberately simple.  Each message is a frame consisting of
+// a four-byte, big-endian payload length followed by the payload.  A request
+// payload begins with an operation byte: 'I' followed by a count and that many
+// length-prefixed strings asks the server to intern the strings, and 'S'
+// followed by a count and that many Eqs asks the server for the Eqs' strings.
+// A response payload begins with a status byte: 0 followed by the requested
+// Eqs or length-prefixed strings, in order, or 1 followed by an error message.
+// All counts, lengths, and Eqs are unsigned varints.
+package registry
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// These constants represent request operations.
+const (
+	opIntern = 'I' // Intern strings and return their Eqs
+	opString = 'S' // Return the strings of a list of Eqs
+)
+
+// These constants represent response statuses.
+const (
+	statusOK  = 0 // Request succeeded
+	statusErr = 1 // Request failed; an error message follows
+)
+
+// maxFrame is the size of the largest frame either side accepts.  It guards
+// against corrupt frames exhausting memory.
+const maxFrame = 1 << 30
+
+// ErrServerClosed is returned by Server.Serve after a call to Server.Close.
+var ErrServerClosed = errors.New("Registry server closed")
+
+// A ServerError reports a request that the server received but rejected, for
+// example because it asked for the string of an unknown Eq.
+type ServerError struct {
+	Msg string // Message sent by the server
+}
+
+// Error returns a ServerError's message.
+func (e *ServerError) Error() string {
+	return e.Msg
+}
+
+// errMalformed reports a frame whose payload cannot be parsed.
+var errMalformed = errors.New("Malformed registry message")
+
+// writeFrame writes a payload to an io.Writer preceded by its length.
+func writeFrame(w io.Writer, payload []byte) error {
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(payload)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readFrame reads a length-prefixed payload from a bufio.Reader.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n > maxFrame {
+		return nil, fmt.Errorf("Registry frame of %d bytes exceeds the maximum of %d", n, maxFrame)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// appendStrings appends a count and that many length-prefixed strings to a
+// payload.
+func appendStrings(buf []byte, strs []string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(strs)))
+	for _, s := range strs {
+		buf = binary.AppendUvarint(buf, uint64(len(s)))
+		buf = append(buf, s...)
+	}
+	return buf
+}
+
+// appendUints appends a count and that many unsigned varints to a payload.
+func appendUints[T ~uint64](buf []byte, vals []T) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(vals)))
+	for _, v := range vals {
+		buf = binary.AppendUvarint(buf, uint64(v))
+	}
+	return buf
+}
+
+// A decoder parses the body of a payload.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+// uint parses an unsigned varint.
+func (d *decoder) uint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errMalformed
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// count parses a count of items, each of which occupies at least one byte.
+func (d *decoder) count() int {
+	n := d.uint()
+	if n > uint64(len(d.buf)) {
+		d.err = errMalformed
+		return 0
+	}
+	return int(n)
+}
+
+// string parses a length-prefixed string.
+func (d *decoder) string() string {
+	n := d.uint()
+	if d.err != nil {
+		return ""
+	}
+	if n > uint64(len(d.buf)) {
+		d.err = errMalformed
+		return ""
+	}
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return s
+}
+
+// strings parses a count and that many length-prefixed strings.
+func (d *decoder) strings() []string {
+	strs := make([]string, d.count())
+	for i := range strs {
+		strs[i] = d.string()
+	}
+	return strs
+}
+
+// uints parses a count and that many unsigned varints.
+func uints[T ~uint64](d *decoder) []T {
+	vals := make([]T, d.count())
+	for i := range vals {
+		vals[i] = T(d.uint())
+	}
+	return vals
+}
+
+// done returns the decoder's error, if any, or an error if any of the payload
+// remains unparsed.
+func (d *decoder) done() error {
+	if d.err == nil && len(d.buf) > 0 {
+		d.err = errMalformed
+	}
+	return d.err
+}
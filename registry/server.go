@@ -0,0 +1,156 @@
+// This file provides the registry server, which owns the authoritative Eq
+// table.
+
+package registry
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/spakin/intern"
+)
+
+// A Server answers clients' requests to intern strings and to convert Eqs
+// back to strings using a single intern.EqTable.
+type Server struct {
+	table     *intern.EqTable
+	mu        sync.Mutex                // Mutex protecting the fields below
+	listeners map[net.Listener]struct{} // Listeners passed to Serve
+	conns     map[net.Conn]struct{}     // Open client connections
+	closed    bool                      // Whether Close has been called
+}
+
+// NewServer returns a Server that shares a given intern.EqTable with its
+// clients.  Pass intern.DefaultEqTable() to share the global Eq table, so
+// that Eqs the server process creates with intern.NewEq match those its
+// clients receive.
+func NewServer(t *intern.EqTable) *Server {
+	return &Server{
+		table:     t,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// Serve accepts connections on a listener, such as one returned by
+// net.Listen("unix", path), and answers each connection's requests in its
+// own goroutine.  Serve always returns a non-nil error; after Close, the
+// error is ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, l)
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		go s.ServeConn(c)
+	}
+}
+
+// ServeConn answers requests arriving on a single connection until the
+// client disconnects or the server is closed.  It closes the connection
+// before returning.
+func (s *Server) ServeConn(c net.Conn) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		c.Close()
+		return
+	}
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+
+	r := bufio.NewReader(c)
+	w := bufio.NewWriter(c)
+	for {
+		req, err := readFrame(r)
+		if err != nil {
+			return
+		}
+		if err = writeFrame(w, s.handle(req)); err != nil {
+			return
+		}
+		if err = w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Close stops all listeners passed to Serve and closes all client
+// connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	var errs []error
+	for l := range s.listeners {
+		errs = append(errs, l.Close())
+	}
+	for c := range s.conns {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// handle answers a single request and returns the response payload.
+func (s *Server) handle(req []byte) []byte {
+	if len(req) == 0 {
+		return errorResponse(errMalformed)
+	}
+	d := &decoder{buf: req[1:]}
+	switch req[0] {
+	case opIntern:
+		strs := d.strings()
+		if err := d.done(); err != nil {
+			return errorResponse(err)
+		}
+		eqs, err := s.table.Intern(strs)
+		if err != nil {
+			return errorResponse(err)
+		}
+		return appendUints([]byte{statusOK}, eqs)
+	case opString:
+		eqs := uints[intern.Eq](d)
+		if err := d.done(); err != nil {
+			return errorResponse(err)
+		}
+		strs := make([]string, len(eqs))
+		for i, e := range eqs {
+			var ok bool
+			strs[i], ok = s.table.LookupString(e)
+			if !ok {
+				return errorResponse(fmt.Errorf("Eq %d is unknown", uint64(e)))
+			}
+		}
+		return appendStrings([]byte{statusOK}, strs)
+	default:
+		return errorResponse(errMalformed)
+	}
+}
+
+// errorResponse returns a response payload that reports an error.
+func errorResponse(err error) []byte {
+	return append([]byte{statusErr}, err.Error()...)
+}
@@ -0,0 +1,56 @@
+// This file provides unit tests for the registry server.
+
+package registry_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/spakin/intern"
+	"github.com/spakin/intern/registry"
+)
+
+// TestServerConn serves a single in-memory connection and ensures that the
+// server shares its table with the process that owns it.
+func TestServerConn(t *testing.T) {
+	table := intern.NewEqTable()
+	e := table.NewEq("Scarecrow")
+	srv := registry.NewServer(table)
+	sConn, cConn := net.Pipe()
+	go srv.ServeConn(sConn)
+	c := registry.NewTable(cConn)
+	defer c.Close()
+	if e2 := c.NewEq("Scarecrow"); e2 != e {
+		t.Fatalf("Expected Eq %d but saw %d", e, e2)
+	}
+	e2 := c.NewEq("Tin Woodman")
+	if s := table.String(e2); s != "Tin Woodman" {
+		t.Fatalf("Expected %q but saw %q", "Tin Woodman", s)
+	}
+}
+
+// TestServerErrors ensures that the server reports requests it cannot
+// satisfy without dropping the connection.
+func TestServerErrors(t *testing.T) {
+	table := intern.NewEqTable()
+	table.NewEq("Glinda")
+	srv := registry.NewServer(table)
+	sConn, cConn := net.Pipe()
+	go srv.ServeConn(sConn)
+	c := registry.NewTable(cConn)
+	defer c.Close()
+	var se *registry.ServerError
+	if _, err := c.Strings([]intern.Eq{99}); !errors.As(err, &se) {
+		t.Fatalf("Expected a ServerError but saw %v", err)
+	}
+	if eqs, err := c.Intern([]string{"Glinda"}); err != nil || table.String(eqs[0]) != "Glinda" {
+		t.Fatalf("Failed to intern %q after an error: %v", "Glinda", err)
+	}
+
+	// A closed server accepts no new connections.
+	srv.Close()
+	if err := srv.Serve(nil); err != registry.ErrServerClosed {
+		t.Fatalf("Expected ErrServerClosed but saw %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+// This file provides support for pinning particular strings to particular
+// symbols.
+
+package intern
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// conflict returns a PkgError that reports a reservation conflict.
+func conflict(s string, format string, args ...interface{}) error {
+	return &PkgError{
+		Code: ErrConflict,
+		Str:  s,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// badReservation returns a PkgError that reports a malformed reservation.
+func badReservation(s string, format string, args ...interface{}) error {
+	return &PkgError{
+		Code: ErrBadReservation,
+		Str:  s,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// checkEqs ensures that a set of strings can be pinned to a set of Eqs.
+func (st *state) checkEqs(m map[string]Eq) error {
+	seen := make(map[Eq]string, len(m))
+	for s, sym := range m {
+		if sym == 0 {
+			return badReservation(s, "Unable to reserve %q as Eq 0", s)
+		}
+		if _, ok := st.inlineEq(s); ok || (st.inline.Load() && symbol(sym)&inlineTag != 0) {
+			return badReservation(s, "Unable to reserve %q as Eq %d; inline Eqs cannot be reserved", s, sym)
+		}
+		if s2, ok := seen[sym]; ok {
+			return conflict(s, "Unable to reserve both %q and %q as Eq %d", s2, s, sym)
+		}
+		seen[sym] = s
+		if old, ok := st.lookupUnfrozen(s); ok && Eq(old) != sym {
+			return conflict(s, "Unable to reserve %q as Eq %d; it is already Eq %d", s, sym, old)
+		}
+		if s2, ok := st.symToStr[symbol(sym)]; ok && s2 != s {
+			return conflict(s, "Unable to reserve %q as Eq %d; it is already %q", s, sym, s2)
+		}
+	}
+	return nil
+}
+
+// pinEqs pins each of a set of strings to the corresponding Eq.  It assumes
+// the pins were already validated by checkEqs.  Strings that are already
+// pinned are skipped so that they are not indexed twice.
+func (st *state) pinEqs(m map[string]Eq) {
+	for s, sym := range m {
+		if _, ok := st.lookupUnfrozen(s); ok {
+			continue
+		}
+		st.setSym(s, symbol(sym))
+	}
+}
+
+// ReserveEqs pins each of the given strings to the corresponding Eq.
+// Subsequent calls to NewEq return the reserved Eq for a reserved string and
+// never assign a reserved Eq to any other string.  This lets a program share
+// Eqs with other programs or compile Eqs into constants (see
+// cmd/interngen).  ReserveEqs returns an error, and reserves nothing, if any
+// Eq is zero, if two strings are pinned to the same Eq, or if a string or an
+// Eq was already assigned differently.  In EqInlineMode (see SetEqMode), it
+// also returns an error if a string is short enough to be packed into an Eq
+// or if an Eq has its high bit set.  Reserving a string that is already
+// assigned exactly the given Eq is not an error.  Reservations last until the
+// next call to ForgetAllEqs.
+func ReserveEqs(m map[string]Eq) error {
+	eq.Lock()
+	defer eq.Unlock()
+	if err := eq.checkFrozen("", "Eq"); err != nil {
+		return err
+	}
+	err := eq.checkEqs(m)
+	if err != nil {
+		return err
+	}
+	eq.pinEqs(m)
+	return nil
+}
+
+// ReserveLGEs pins each of the given strings to the corresponding LGE.
+// Because an LGE encodes its position relative to all other LGEs, the
+// reservations must be consistent with each other and with all existing LGEs.
+// In practice, this means that the given LGEs should have been produced by
+// NewLGEMulti (possibly in a different program, as cmd/interngen does) with no
+// other LGEs allocated, and that ReserveLGEs should be called before any other
+// LGE is allocated.  ReserveLGEs returns an error, and reserves nothing, if
+// the reservations cannot be honored or if inline prefixes are enabled (see
+// SetLGEInlinePrefix).  Note that RemapAllLGEs does not preserve
+// reservations.
+func ReserveLGEs(m map[string]LGE) error {
+	type reservation struct {
+		str string
+		sym symbol
+	}
+	lge.Lock()
+	defer lge.Unlock()
+	if err := lge.checkFrozen("", "LGE"); err != nil {
+		return err
+	}
+	if lge.prefixLen > 0 {
+		return badReservation("", "Unable to reserve LGEs while inline prefixes are enabled")
+	}
+
+	// Sort the reservations from the root of the tree downwards.  An
+	// LGE's depth in the tree is determined by its number of trailing
+	// zero bits.
+	rs := make([]reservation, 0, len(m))
+	for s, sym := range m {
+		if sym == 0 {
+			return badReservation(s, "Unable to reserve %q as LGE 0", s)
+		}
+		rs = append(rs, reservation{str: s, sym: symbol(sym)})
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		zi := bits.TrailingZeros64(uint64(rs[i].sym))
+		zj := bits.TrailingZeros64(uint64(rs[j].sym))
+		if zi != zj {
+			return zi > zj
+		}
+		return rs[i].str < rs[j].str
+	})
+
+	// Insert each string into a copy of the tree, and ensure it lands
+	// where it was expected to land.
+	t := lge.tree.clone()
+	for _, r := range rs {
+		var sym symbol
+		var err error
+		t, sym, err = t.insert(r.str)
+		if err != nil {
+			return err
+		}
+		if sym != r.sym {
+			return conflict(r.str, "Unable to reserve %q as LGE %d; it would be LGE %d", r.str, r.sym, sym)
+		}
+	}
+
+	// Commit to the new tree.
+	lge.tree = t
+	for _, r := range rs {
+		lge.symToStr[r.sym] = r.str
+		lge.strToSym[r.str] = r.sym
+	}
+	return nil
+}
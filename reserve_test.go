@@ -0,0 +1,169 @@
+// This file provides unit tests for symbol reservations.
+
+package intern_test
+
+import (
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// TestReserveEqs ensures that reserved Eqs are honored and never reassigned.
+func TestReserveEqs(t *testing.T) {
+	// Reserve a few Eqs.
+	intern.ForgetAllEqs()
+	rsv := map[string]intern.Eq{
+		"Dorothy Gale": 3,
+		"Toto":         1,
+		"Glinda":       6,
+	}
+	err := intern.ReserveEqs(rsv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure that reserved strings map to their reserved Eqs.
+	for s, sym := range rsv {
+		if e := intern.NewEq(s); e != sym {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, sym, e)
+		}
+		if sym.String() != s {
+			t.Fatalf("Expected %d to map to %q but saw %q", sym, s, sym)
+		}
+	}
+
+	// Ensure that no other string is assigned a reserved Eq.
+	for _, s := range ozChars {
+		e := intern.NewEq(s)
+		if r, ok := rsv[s]; ok {
+			if e != r {
+				t.Fatalf("Expected %q to map to %d but saw %d", s, r, e)
+			}
+			continue
+		}
+		for rs, r := range rsv {
+			if e == r {
+				t.Fatalf("%q was assigned Eq %d, which is reserved for %q", s, e, rs)
+			}
+		}
+		if e.String() != s {
+			t.Fatalf("Expected %q but saw %q", s, e)
+		}
+	}
+}
+
+// TestReserveEqsConflict ensures that conflicting reservations are rejected.
+func TestReserveEqsConflict(t *testing.T) {
+	intern.ForgetAllEqs()
+	x := intern.NewEq("x")
+	for _, rsv := range []map[string]intern.Eq{
+		{"y": x},
+		{"x": x + 1},
+		{"y": 10, "z": 10},
+		{"y": 11, "x": x + 1},
+	} {
+		err := intern.ReserveEqs(rsv)
+		if e, ok := err.(*intern.PkgError); !ok || e.Code != intern.ErrConflict {
+			t.Fatalf("Expected ErrConflict for %v but saw %v", rsv, err)
+		}
+	}
+	if e := intern.NewEq("y"); e == 11 {
+		t.Fatal("A failed reservation was partially applied")
+	}
+	err := intern.ReserveEqs(map[string]intern.Eq{"z": 0})
+	if e, ok := err.(*intern.PkgError); !ok || e.Code != intern.ErrBadReservation {
+		t.Fatalf("Expected ErrBadReservation but saw %v", err)
+	}
+	err = intern.ReserveEqs(map[string]intern.Eq{"x": x})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReserveEqsTwice ensures that repeating a reservation does not index
+// its string twice.
+func TestReserveEqsTwice(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.EnableEqSearchIndex(false)
+	defer intern.ForgetAllEqs()
+	intern.EnableEqSearchIndex(true)
+	rsv := map[string]intern.Eq{"alphabet": intern.NewEq("alphabet")}
+	if err := intern.ReserveEqs(rsv); err != nil {
+		t.Fatal(err)
+	}
+	if eqs := intern.ContainsEqs("alph"); len(eqs) != 1 {
+		t.Fatalf("Expected 1 Eq but saw %v", eqs)
+	}
+}
+
+// TestReserveLGEs ensures that LGEs produced by NewLGEMulti can be reserved
+// in an empty symbol table and that subsequent LGEs respect them.
+func TestReserveLGEs(t *testing.T) {
+	// Determine the LGEs an empty symbol table would assign.
+	intern.ForgetAllLGEs()
+	syms, err := intern.NewLGEMulti(ozChars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsv := make(map[string]intern.LGE, len(ozChars))
+	for i, s := range ozChars {
+		rsv[s] = syms[i]
+	}
+
+	// Reserve those LGEs in a fresh symbol table.
+	intern.ForgetAllLGEs()
+	err = intern.ReserveLGEs(rsv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for s, sym := range rsv {
+		if sym.String() != s {
+			t.Fatalf("Expected %d to map to %q but saw %q", sym, s, sym)
+		}
+	}
+
+	// Allocate more LGEs and ensure they compare properly.
+	more := []string{"Alice", "Mad Hatter", "Queen of Hearts", "White Rabbit"}
+	intern.PreLGEMulti(more)
+	for _, s := range more {
+		sym, err := intern.NewLGE(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for s2, sym2 := range rsv {
+			switch {
+			case s < s2 && sym < sym2:
+			case s > s2 && sym > sym2:
+			default:
+				t.Fatalf("Strings %q and %q mapped incorrectly to LGEs %d and %d", s, s2, sym, sym2)
+			}
+		}
+	}
+}
+
+// TestReserveLGEsConflict ensures that inconsistent LGE reservations are
+// rejected.
+func TestReserveLGEsConflict(t *testing.T) {
+	intern.ForgetAllLGEs()
+	a, err := intern.NewLGE("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rsv := range []map[string]intern.LGE{
+		{"b": a},
+		{"a": a / 2},
+	} {
+		err = intern.ReserveLGEs(rsv)
+		if e, ok := err.(*intern.PkgError); !ok || e.Code != intern.ErrConflict {
+			t.Fatalf("Expected ErrConflict for %v but saw %v", rsv, err)
+		}
+	}
+	err = intern.ReserveLGEs(map[string]intern.LGE{"b": 0})
+	if e, ok := err.(*intern.PkgError); !ok || e.Code != intern.ErrBadReservation {
+		t.Fatalf("Expected ErrBadReservation but saw %v", err)
+	}
+	err = intern.ReserveLGEs(map[string]intern.LGE{"a": a})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,155 @@
+// This file provides support for withholding ranges of Eqs from NewEq and for
+// loading Eq reservations from a configuration file.
+
+package intern
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A symRange represents an inclusive range of symbols.
+type symRange struct {
+	lo, hi symbol
+}
+
+// inReservedRange returns the reserved range containing a given symbol, if
+// any.
+func (st *state) inReservedRange(sym symbol) (symRange, bool) {
+	for _, r := range st.ranges {
+		if sym >= r.lo && sym <= r.hi {
+			return r, true
+		}
+	}
+	return symRange{}, false
+}
+
+// checkEqRange ensures that a range of Eqs can be reserved.
+func (st *state) checkEqRange(lo, hi Eq) error {
+	if lo == 0 || lo > hi || hi == ^Eq(0) {
+		return badReservation("", "Unable to reserve invalid Eq range %d-%d", lo, hi)
+	}
+	if st.inline.Load() && symbol(hi)&inlineTag != 0 {
+		return badReservation("", "Unable to reserve Eq range %d-%d; inline Eqs cannot be reserved", lo, hi)
+	}
+	for sym, s := range st.symToStr {
+		if symbol(lo) <= sym && sym <= symbol(hi) {
+			return conflict(s, "Unable to reserve Eq range %d-%d; Eq %d is already %q", lo, hi, sym, s)
+		}
+	}
+	return nil
+}
+
+// ReserveEqRange withholds an inclusive range of Eqs from NewEq.  Strings can
+// still be pinned to Eqs within the range using ReserveEqs.  Reserving a
+// range ahead of time lets a program add well-known strings to the range
+// later without risk of colliding with Eqs NewEq has already assigned.
+// ReserveEqRange returns an error if the range is empty, includes 0 or the
+// largest possible Eq, or includes an Eq that is already assigned.  Call it
+// before pinning any strings within the range.
+func ReserveEqRange(lo, hi Eq) error {
+	eq.Lock()
+	defer eq.Unlock()
+	if err := eq.checkFrozen("", "Eq"); err != nil {
+		return err
+	}
+	err := eq.checkEqRange(lo, hi)
+	if err != nil {
+		return err
+	}
+	eq.ranges = append(eq.ranges, symRange{lo: symbol(lo), hi: symbol(hi)})
+	return nil
+}
+
+// LoadEqReservations reads Eq reservations from a configuration file and
+// applies them as if by ReserveEqRange and ReserveEqs.  This is intended for
+// Eqs shared with other programs, which need to remain the same across
+// deployments.  The file is line-oriented.  Blank lines and lines beginning
+// with "#" are ignored.  A line of the form "lo-hi" reserves the range of Eqs
+// from lo through hi.  Any other line must contain an Eq, whitespace, and the
+// string to pin to that Eq, either verbatim or as a double-quoted Go string
+// literal.  For example,
+//
+//	# Eqs 1-1000 are set aside for well-known strings.
+//	1-1000
+//	1 GET
+//	2 POST
+//	3 "Content-Type "
+//
+// All ranges are reserved before any strings are pinned.  LoadEqReservations
+// returns an error, and reserves nothing, if the file is malformed or any
+// reservation cannot be honored.
+func LoadEqReservations(r io.Reader) error {
+	// Parse the entire file.
+	var ranges []symRange
+	pins := make(map[string]Eq)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 1 {
+			// Parse a range.
+			lohi := strings.SplitN(fields[0], "-", 2)
+			if len(lohi) != 2 {
+				return badReservation("", "Line %d: expected a range or an Eq and a string", lineNum)
+			}
+			lo, err1 := strconv.ParseUint(lohi[0], 10, 64)
+			hi, err2 := strconv.ParseUint(lohi[1], 10, 64)
+			if err1 != nil || err2 != nil {
+				return badReservation("", "Line %d: invalid Eq range %q", lineNum, fields[0])
+			}
+			ranges = append(ranges, symRange{lo: symbol(lo), hi: symbol(hi)})
+			continue
+		}
+
+		// Parse an Eq and a string.
+		sym, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return badReservation("", "Line %d: invalid Eq %q", lineNum, fields[0])
+		}
+		s := strings.TrimSpace(line[len(fields[0]):])
+		if s[0] == '"' {
+			s, err = strconv.Unquote(s)
+			if err != nil {
+				return badReservation("", "Line %d: invalid string literal", lineNum)
+			}
+		}
+		if _, ok := pins[s]; ok {
+			return badReservation(s, "Line %d: %q is already reserved", lineNum, s)
+		}
+		pins[s] = Eq(sym)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// Validate all reservations then apply them.
+	eq.Lock()
+	defer eq.Unlock()
+	if err := eq.checkFrozen("", "Eq"); err != nil {
+		return err
+	}
+	for i, r := range ranges {
+		err := eq.checkEqRange(Eq(r.lo), Eq(r.hi))
+		if err != nil {
+			return err
+		}
+		for _, r2 := range ranges[:i] {
+			if r.lo <= r2.hi && r2.lo <= r.hi {
+				return conflict("", "Eq ranges %d-%d and %d-%d overlap", r2.lo, r2.hi, r.lo, r.hi)
+			}
+		}
+	}
+	err := eq.checkEqs(pins)
+	if err != nil {
+		return err
+	}
+	eq.ranges = append(eq.ranges, ranges...)
+	eq.pinEqs(pins)
+	return nil
+}
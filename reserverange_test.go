@@ -0,0 +1,101 @@
+// This file provides unit tests for reserved Eq ranges and reservation
+// files.
+
+package intern_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// TestReserveEqRange ensures that NewEq never assigns an Eq within a reserved
+// range but that strings can be pinned within the range.
+func TestReserveEqRange(t *testing.T) {
+	// Reserve a range and pin a string within it.
+	intern.ForgetAllEqs()
+	const lo, hi = 5, 50
+	err := intern.ReserveEqRange(lo, hi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = intern.ReserveEqs(map[string]intern.Eq{"Wizard of Oz": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure that no other string lands in the range.
+	for _, s := range ozChars {
+		e := intern.NewEq(s)
+		if s == "Wizard of Oz" {
+			if e != 10 {
+				t.Fatalf("Expected %q to map to 10 but saw %d", s, e)
+			}
+			continue
+		}
+		if e >= lo && e <= hi {
+			t.Fatalf("%q was assigned reserved Eq %d", s, e)
+		}
+	}
+
+	// Ensure that invalid and conflicting ranges are rejected.
+	for _, r := range [][2]intern.Eq{{0, 3}, {9, 8}, {1, 2}} {
+		err = intern.ReserveEqRange(r[0], r[1])
+		if err == nil {
+			t.Fatalf("Failed to reject Eq range %d-%d", r[0], r[1])
+		}
+	}
+}
+
+// TestLoadEqReservations ensures that reservations can be read from a
+// configuration file.
+func TestLoadEqReservations(t *testing.T) {
+	// Load a valid configuration.
+	intern.ForgetAllEqs()
+	cfg := `# Well-known strings
+1-100
+
+1 GET
+2   POST
+3 "Content-Type "
+200 Tin Woodman
+`
+	err := intern.LoadEqReservations(strings.NewReader(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for s, sym := range map[string]intern.Eq{
+		"GET":           1,
+		"POST":          2,
+		"Content-Type ": 3,
+		"Tin Woodman":   200,
+	} {
+		if e := intern.NewEq(s); e != sym {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, sym, e)
+		}
+	}
+	if e := intern.NewEq("Dorothy Gale"); e <= 100 {
+		t.Fatalf("Expected an Eq greater than 100 but saw %d", e)
+	}
+
+	// Ensure that malformed and conflicting configurations are rejected
+	// without being partially applied.
+	for _, cfg := range []string{
+		"1-\n",
+		"x Toto\n",
+		"300 \"Toto\n",
+		"300 Toto\n301 Toto\n",
+		"300-400\n350-500\n",
+		"500-600\n1 Toto\n",
+		"500-600\n150-250\n",
+	} {
+		err = intern.LoadEqReservations(strings.NewReader(cfg))
+		if err == nil {
+			t.Fatalf("Failed to reject configuration %q", cfg)
+		}
+		if e := intern.NewEq("Toto"); e >= 300 && e <= 301 {
+			t.Fatalf("Configuration %q was partially applied", cfg)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// This file provides substring and regular-expression searches over the set
+// of interned Eq strings.
+
+package intern
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ngramLen is the length in bytes of the n-grams in an ngramIndex.
+const ngramLen = 3
+
+// An ngramIndex maps each n-gram (specifically, trigram) that appears in any
+// interned string to the symbols of all strings in which it appears.  A
+// string contains a given substring only if it contains every n-gram of the
+// substring, so the index can quickly narrow a search to a few candidates.
+type ngramIndex struct {
+	postings map[uint32][]symbol // Symbols of the strings containing each n-gram
+}
+
+// ngram returns the n-gram beginning at a given position in a string.
+func ngram(s string, i int) uint32 {
+	return uint32(s[i])<<16 | uint32(s[i+1])<<8 | uint32(s[i+2])
+}
+
+// insert adds a string and its symbol to an ngramIndex.
+func (ni *ngramIndex) insert(s string, sym symbol) {
+	var seen map[uint32]struct{}
+	for i := 0; i+ngramLen <= len(s); i++ {
+		g := ngram(s, i)
+		if seen == nil {
+			seen = make(map[uint32]struct{}, len(s))
+		} else if _, dup := seen[g]; dup {
+			continue
+		}
+		seen[g] = struct{}{}
+		ni.postings[g] = append(ni.postings[g], sym)
+	}
+}
+
+// candidates returns the symbols of all strings that might contain a given
+// substring.  The substring must be at least ngramLen bytes long.
+func (ni *ngramIndex) candidates(substr string) []symbol {
+	var best []symbol
+	for i := 0; i+ngramLen <= len(substr); i++ {
+		p, ok := ni.postings[ngram(substr, i)]
+		if !ok {
+			return nil
+		}
+		if best == nil || len(p) < len(best) {
+			best = p
+		}
+	}
+	return best
+}
+
+// eachSym calls a function on each of a state's strings and its symbol in no
+// particular order.  The caller must hold the state's lock.
+func (st *state) eachSym(f func(s string, sym symbol)) {
+	if ft := st.frozenTable(); ft != nil {
+		for r := 0; r < ft.size(); r++ {
+			sym := ft.symAt(r)
+			s, _ := ft.str(sym)
+			f(s, sym)
+		}
+		return
+	}
+	for sym, s := range st.symToStr {
+		f(s, sym)
+	}
+}
+
+// str returns the string associated with a symbol, whether or not the state
+// is frozen.  The caller must hold the state's lock.
+func (st *state) str(sym symbol) string {
+	if ft := st.frozenTable(); ft != nil {
+		s, _ := ft.str(sym)
+		return s
+	}
+	return st.symToStr[sym]
+}
+
+// search returns, in order of their strings, the Eqs of all strings that
+// contain a given literal substring and satisfy a given predicate.  The
+// substring may be empty.
+func (st *state) search(lit string, match func(s string) bool) []Eq {
+	type result struct {
+		str string
+		sym symbol
+	}
+	var rs []result
+	st.RLock()
+	if st.ngrams != nil && len(lit) >= ngramLen {
+		// Consult the index.
+		for _, sym := range st.ngrams.candidates(lit) {
+			s := st.str(sym)
+			if strings.Contains(s, lit) && match(s) {
+				rs = append(rs, result{str: s, sym: sym})
+			}
+		}
+	} else {
+		// Scan all strings.
+		st.eachSym(func(s string, sym symbol) {
+			if strings.Contains(s, lit) && match(s) {
+				rs = append(rs, result{str: s, sym: sym})
+			}
+		})
+	}
+	st.RUnlock()
+	sort.Slice(rs, func(i, j int) bool { return rs[i].str < rs[j].str })
+	eqs := make([]Eq, len(rs))
+	for i, r := range rs {
+		eqs[i] = Eq(r.sym)
+	}
+	return eqs
+}
+
+// requiredLiteral returns the longest literal string that must appear in
+// every match of a regular expression.  It returns the empty string if it
+// finds no such literal.
+func requiredLiteral(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return ""
+		}
+		buf := make([]byte, 0, len(re.Rune))
+		for _, r := range re.Rune {
+			buf = utf8.AppendRune(buf, r)
+		}
+		return string(buf)
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiteral(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return requiredLiteral(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		best := ""
+		for _, sub := range re.Sub {
+			if lit := requiredLiteral(sub); len(lit) > len(best) {
+				best = lit
+			}
+		}
+		return best
+	}
+	return ""
+}
+
+// EnableEqSearchIndex enables or disables an index that speeds up
+// ContainsEqs and FindEqs.  The index records the Eqs of all strings
+// containing each three-byte sequence, which can take several times as much
+// memory as the strings themselves.  Once enabled, the index is kept up to
+// date as Eqs are added and survives ForgetAllEqs and FreezeEqs.
+func EnableEqSearchIndex(enable bool) {
+	eq.Lock()
+	defer eq.Unlock()
+	if !enable {
+		eq.ngrams = nil
+		return
+	}
+	if eq.ngrams != nil {
+		return
+	}
+	eq.ngrams = &ngramIndex{postings: make(map[uint32][]symbol)}
+	eq.eachSym(eq.ngrams.insert)
+}
+
+// ContainsEqs returns the Eqs of all interned strings that contain a given
+// substring, in order of their strings.  If the search index is enabled (see
+// EnableEqSearchIndex) and the substring is at least three bytes long,
+// ContainsEqs examines only strings that contain every three-byte sequence in
+// the substring.  Otherwise, it examines every interned string.
+func ContainsEqs(substr string) []Eq {
+	return eq.search(substr, func(string) bool { return true })
+}
+
+// FindEqs returns the Eqs of all interned strings that match a regular
+// expression, in order of their strings.  If the search index is enabled
+// (see EnableEqSearchIndex) and every match of the regular expression must
+// contain a literal string at least three bytes long, FindEqs examines only
+// strings that contain that literal.  Otherwise, it examines every interned
+// string.
+func FindEqs(re *regexp.Regexp) []Eq {
+	lit := ""
+	if parsed, err := syntax.Parse(re.String(), syntax.Perl); err == nil {
+		lit = requiredLiteral(parsed.Simplify())
+	}
+	return eq.search(lit, re.MatchString)
+}
@@ -0,0 +1,72 @@
+// This file provides unit tests for substring and regular-expression searches
+// over interned strings.
+
+package intern_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// expectEqs fails a test if a list of Eqs does not represent exactly the
+// given strings in the given order.
+func expectEqs(t *testing.T, what string, got []intern.Eq, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %d strings but saw %d", what, len(want), len(got))
+	}
+	for i, e := range got {
+		if e.String() != want[i] {
+			t.Fatalf("%s: expected %q but saw %q", what, want[i], e)
+		}
+	}
+}
+
+// TestSearchEqs compares ContainsEqs and FindEqs to a brute-force search with
+// and without the search index and with and without freezing the table.
+func TestSearchEqs(t *testing.T) {
+	intern.ForgetAllEqs()
+	defer intern.EnableEqSearchIndex(false)
+	defer intern.ForgetAllEqs()
+	intern.NewEqMulti(ozChars)
+	intern.NewEqMulti(generateRandomStrings(1000))
+	substrs := []string{"", "o", "in", "Kin", "King K", "Witch of the", "Zebra", "ing"}
+	patterns := []string{"^King", "Witch of the (North|South)$", "(?i)jack", "[0-9]", "Queen.*oo", "(Bell|Button)-"}
+	for pass := 0; pass < 3; pass++ {
+		switch pass {
+		case 1:
+			intern.EnableEqSearchIndex(true)
+			intern.NewEqMulti([]string{"King Kong", "Kingfisher"}) // Update the index.
+		case 2:
+			intern.FreezeEqs()
+		}
+		all := intern.CompleteEqs("", 0)
+		for _, substr := range substrs {
+			var want []string
+			for _, e := range all {
+				if strings.Contains(e.String(), substr) {
+					want = append(want, e.String())
+				}
+			}
+			expectEqs(t, "ContainsEqs("+substr+")", intern.ContainsEqs(substr), sortedCopy(want))
+		}
+		for _, pat := range patterns {
+			re := regexp.MustCompile(pat)
+			var want []string
+			for _, e := range all {
+				if re.MatchString(e.String()) {
+					want = append(want, e.String())
+				}
+			}
+			expectEqs(t, "FindEqs("+pat+")", intern.FindEqs(re), sortedCopy(want))
+		}
+	}
+
+	// Ensure that the index survives ForgetAllEqs.
+	intern.ForgetAllEqs()
+	intern.NewEq("Tin Woodman")
+	expectEqs(t, "ContainsEqs(Woodman)", intern.ContainsEqs("Woodman"), []string{"Tin Woodman"})
+}
@@ -0,0 +1,263 @@
+// This file provides support for writing frozen symbol tables to files and
+// reading them back.
+
+package intern
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// snapshotMagic begins every snapshot.  The final byte is a version number.
+const snapshotMagic = "intern\x00\x01"
+
+// maxSnapshotString is the length of the longest string a snapshot may
+// contain.  It guards against corrupt snapshots exhausting memory.
+const maxSnapshotString = 1 << 30
+
+// maxSnapshotPrealloc is the largest number of entries for which
+// readSnapshot allocates space before reading them.  Larger tables grow as
+// their entries are read so a corrupt header cannot exhaust memory.
+const maxSnapshotPrealloc = 1 << 16
+
+// badSnapshot returns a PkgError that reports a malformed snapshot.
+func badSnapshot(format string, args ...interface{}) error {
+	return &PkgError{
+		Code: ErrBadSnapshot,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// snapshotIndex returns a minimal perfect hash of a state's mappings, reusing
+// the frozen table's index if the state is frozen.
+func (st *state) snapshotIndex() *mph {
+	if ft := st.frozenTable(); ft != nil {
+		return ft.index()
+	}
+	st.RLock()
+	defer st.RUnlock()
+	if ft := st.frozenTable(); ft != nil {
+		return ft.index()
+	}
+	return newMPH(st.strMap())
+}
+
+// writeSnapshot writes a state's mappings, including the minimal perfect hash
+// used to look up strings, to an io.Writer.  The format is a magic string;
+// a byte indicating the symbol type; the hash seed, key count, and bucket
+// count; each bucket's displacement; and each key's symbol, length, and
+// contents.  All integers are written as unsigned varints.
+func (st *state) writeSnapshot(w io.Writer, kind byte) error {
+	m := st.snapshotIndex()
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 0, 3*binary.MaxVarintLen64)
+	buf = append(buf, snapshotMagic...)
+	buf = append(buf, kind)
+	buf = binary.AppendUvarint(buf, m.seed)
+	buf = binary.AppendUvarint(buf, uint64(len(m.keys)))
+	buf = binary.AppendUvarint(buf, uint64(len(m.disp)))
+	if _, err := bw.Write(buf); err != nil {
+		return err
+	}
+	for _, d := range m.disp {
+		buf = binary.AppendUvarint(buf[:0], uint64(d))
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	for i, k := range m.keys {
+		buf = binary.AppendUvarint(buf[:0], uint64(m.syms[i]))
+		buf = binary.AppendUvarint(buf, uint64(len(k)))
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+		if _, err := bw.WriteString(k); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// readUvarint reads an unsigned varint and reports a truncated snapshot as a
+// PkgError.
+func readUvarint(br *bufio.Reader) (uint64, error) {
+	v, err := binary.ReadUvarint(br)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, badSnapshot("Snapshot is truncated")
+	}
+	return v, err
+}
+
+// readSnapshot reads a snapshot written by writeSnapshot and returns a frozen
+// table containing its mappings.
+func readSnapshot(r io.Reader, kind byte) (*hashStore, error) {
+	// Read and validate the header.
+	br := bufio.NewReader(r)
+	hdr := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, badSnapshot("Snapshot is truncated")
+	}
+	if string(hdr[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, badSnapshot("Unrecognized snapshot format")
+	}
+	if hdr[len(snapshotMagic)] != kind {
+		return nil, badSnapshot("Snapshot is not of the expected symbol type")
+	}
+	var hv [3]uint64
+	for i := range hv {
+		v, err := readUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		hv[i] = v
+	}
+	seed, n, nb := hv[0], hv[1], hv[2]
+	if n >= mphDirect || nb == 0 || nb > n+1 {
+		return nil, badSnapshot("Snapshot header is corrupt")
+	}
+
+	// Read the hash's displacements.
+	prealloc := n
+	if prealloc > maxSnapshotPrealloc {
+		prealloc = maxSnapshotPrealloc
+	}
+	m := &mph{
+		seed: seed,
+		disp: make([]uint32, 0, prealloc),
+		keys: make([]string, 0, prealloc),
+		syms: make([]symbol, 0, prealloc),
+	}
+	for i := uint64(0); i < nb; i++ {
+		d, err := readUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		if d >= 1<<32 || (d&mphDirect != 0 && d&^mphDirect >= n) {
+			return nil, badSnapshot("Snapshot hash is corrupt")
+		}
+		m.disp = append(m.disp, uint32(d))
+	}
+
+	// Read the keys and their symbols.
+	hs := &hashStore{
+		symToStr: make(map[symbol]string, prealloc),
+		mph:      m,
+	}
+	for i := uint64(0); i < n; i++ {
+		sym, err := readUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		ln, err := readUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		if sym == 0 || ln > maxSnapshotString {
+			return nil, badSnapshot("Snapshot entry %d is corrupt", i)
+		}
+		b := make([]byte, ln)
+		if _, err := io.ReadFull(br, b); err != nil {
+			return nil, badSnapshot("Snapshot is truncated")
+		}
+		if _, dup := hs.symToStr[symbol(sym)]; dup {
+			return nil, badSnapshot("Symbol %d appears more than once in the snapshot", sym)
+		}
+		m.keys = append(m.keys, string(b))
+		m.syms = append(m.syms, symbol(sym))
+		hs.symToStr[symbol(sym)] = string(b)
+	}
+
+	// Ensure that the hash finds every key.
+	for i, k := range m.keys {
+		if sym, ok := m.lookup(k); !ok || sym != m.syms[i] {
+			return nil, badSnapshot("Snapshot hash does not match its contents")
+		}
+	}
+	return hs, nil
+}
+
+// install replaces a state's mappings with those of a frozen store.
+func (st *state) install(fs frozenStore) {
+	st.Lock()
+	defer st.Unlock()
+	st.installLocked(fs)
+}
+
+// installLocked replaces a state's mappings with those of a frozen store.
+// The caller must hold the state's write lock.
+func (st *state) installLocked(fs frozenStore) {
+	st.forgetAll()
+	st.symToStr = nil
+	st.strToSym = nil
+	st.frozen.Store(&frozenTable{fs})
+}
+
+// WriteEqSnapshot writes all Eqs and their strings, along with the index
+// used to look up strings in a frozen table, to an io.Writer.  The table
+// need not be frozen, but if it is, the index is not rebuilt.
+func WriteEqSnapshot(w io.Writer) error {
+	return eq.writeSnapshot(w, 'E')
+}
+
+// ReadEqSnapshot replaces all existing Eqs with those read from an io.Reader,
+// as written by WriteEqSnapshot, and freezes the result (see FreezeEqs).
+// Because the snapshot includes the frozen table's index, reading a snapshot
+// is faster than interning its strings and freezing the table.  In
+// EqInlineMode (see SetEqMode), ReadEqSnapshot rejects snapshots containing
+// strings short enough to be packed into Eqs.  On error, the existing Eqs are
+// left intact.
+func ReadEqSnapshot(r io.Reader) error {
+	hs, err := readSnapshot(r, 'E')
+	if err != nil {
+		return err
+	}
+
+	// Ensure that no string in the snapshot would be packed into an Eq.
+	// Holding the lock keeps SetEqMode from changing the mode between the
+	// check and the installation.
+	eq.Lock()
+	defer eq.Unlock()
+	for sym, s := range hs.symToStr {
+		if _, ok := eq.inlineEq(s); ok || (eq.inline.Load() && sym&inlineTag != 0) {
+			return badSnapshot("Snapshot string %q conflicts with inline Eqs", s)
+		}
+	}
+	eq.installLocked(hs)
+
+	// Recover the probe counts of hashed Eqs.
+	if eq.hashed {
+		eq.recordProbes()
+	}
+	return nil
+}
+
+// WriteLGESnapshot writes all LGEs and their strings, along with the index
+// used to look up strings in a frozen table, to an io.Writer.  The table
+// need not be frozen, but if it is, the index is not rebuilt.  Strings passed
+// to PreLGE but not yet allocated are not written.
+func WriteLGESnapshot(w io.Writer) error {
+	return lge.writeSnapshot(w, 'L')
+}
+
+// ReadLGESnapshot replaces all existing LGEs with those read from an
+// io.Reader, as written by WriteLGESnapshot, and freezes the result (see
+// FreezeLGEs).  The result uses LGEHashStorage; FreezeLGEsWith can convert
+// it to another form of storage.  On error, the existing LGEs are left
+// intact.
+func ReadLGESnapshot(r io.Reader) error {
+	hs, err := readSnapshot(r, 'L')
+	if err != nil {
+		return err
+	}
+
+	// Ensure that the LGEs are ordered consistently with their strings.
+	for r := 1; r < hs.size(); r++ {
+		if prev, sym := hs.symAt(r-1), hs.symAt(r); prev >= sym {
+			return badSnapshot("LGEs %d and %d are out of order", prev, sym)
+		}
+	}
+	lge.install(hs)
+	return nil
+}
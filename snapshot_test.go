@@ -0,0 +1,132 @@
+// This file provides unit tests for writing and reading snapshots of symbol
+// tables.
+
+package intern_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// TestEqSnapshot writes a snapshot of the Eq table, reads it back, and
+// ensures that the result is frozen and complete.
+func TestEqSnapshot(t *testing.T) {
+	for _, freeze := range []bool{false, true} {
+		// Create a table of Eqs and write a snapshot of it.
+		intern.ForgetAllEqs()
+		syms := intern.NewEqMulti(ozChars)
+		if freeze {
+			intern.FreezeEqs()
+		}
+		var buf bytes.Buffer
+		err := intern.WriteEqSnapshot(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Replace the table with the snapshot.
+		intern.ForgetAllEqs()
+		intern.NewEq("Alice")
+		err = intern.ReadEqSnapshot(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, s := range ozChars {
+			if e, ok := intern.LookupEq(s); !ok || e != syms[i] {
+				t.Fatalf("Expected %q to map to %d but saw %d", s, syms[i], e)
+			}
+			if syms[i].String() != s {
+				t.Fatalf("Expected %q but saw %q", s, syms[i])
+			}
+		}
+		if _, ok := intern.LookupEq("Alice"); ok {
+			t.Fatal("Reading a snapshot failed to discard existing Eqs")
+		}
+		var e intern.Eq
+		expectFrozen(t, e.UnmarshalText([]byte("Alice")))
+	}
+	intern.ForgetAllEqs()
+}
+
+// TestLGESnapshot writes a snapshot of the LGE table, reads it back, and
+// ensures that the result is frozen and complete.
+func TestLGESnapshot(t *testing.T) {
+	// Create a table of LGEs and write a snapshot of it.
+	intern.ForgetAllLGEs()
+	defer intern.ForgetAllLGEs()
+	syms, err := intern.NewLGEMulti(ozChars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = intern.WriteLGESnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the table with the snapshot.
+	intern.ForgetAllLGEs()
+	err = intern.ReadLGESnapshot(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range ozChars {
+		l, err := intern.NewLGE(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l != syms[i] || l.String() != s {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, syms[i], l)
+		}
+	}
+	_, err = intern.NewLGE("Alice")
+	expectFrozen(t, err)
+
+	// An LGE snapshot is not an Eq snapshot.
+	err = intern.ReadEqSnapshot(bytes.NewReader(buf.Bytes()))
+	if e, ok := err.(*intern.PkgError); !ok || e.Code != intern.ErrBadSnapshot {
+		t.Fatalf("Expected ErrBadSnapshot but saw %v", err)
+	}
+}
+
+// TestBadSnapshot ensures that corrupt snapshots are rejected and leave the
+// existing table intact.
+func TestBadSnapshot(t *testing.T) {
+	// Write a valid snapshot.
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	syms := intern.NewEqMulti(ozChars)
+	var buf bytes.Buffer
+	err := intern.WriteEqSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := buf.Bytes()
+
+	// A header that claims an enormous table must not cause the table to
+	// be allocated before its entries are read.
+	huge := append([]byte(nil), good[:9]...)
+	huge = binary.AppendUvarint(huge, 1)
+	huge = binary.AppendUvarint(huge, 1<<31-1)
+	huge = binary.AppendUvarint(huge, 1<<30)
+
+	// Corrupt it in various ways.
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("INTERN"), good[6:]...),
+		"truncated": good[:len(good)-10],
+		"contents":  bytes.Replace(good, []byte("Toto"), []byte("Tutu"), 1),
+		"header":    huge,
+	} {
+		err = intern.ReadEqSnapshot(bytes.NewReader(bad))
+		if e, ok := err.(*intern.PkgError); !ok || e.Code != intern.ErrBadSnapshot {
+			t.Fatalf("%s: Expected ErrBadSnapshot but saw %v", name, err)
+		}
+	}
+	if syms[0].String() != ozChars[0] {
+		t.Fatal("A failed snapshot read altered the Eq table")
+	}
+}
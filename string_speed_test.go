@@ -4,6 +4,7 @@ package intern_test
 
 import (
 	"math/rand"
+	"sort"
 	"testing"
 )
 
@@ -75,3 +76,11 @@ func BenchmarkMergeStringMaps(b *testing.B) {
 		m3[k] = Empty{}
 	}
 }
+
+// BenchmarkSortRandomStrings measures the time needed to sort a number of
+// long, randomly generated strings.
+func BenchmarkSortRandomStrings(b *testing.B) {
+	strs := generateRandomStrings(b.N)
+	b.ResetTimer()
+	sort.Strings(strs)
+}
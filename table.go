@@ -0,0 +1,329 @@
+// This file provides Eq and LGE tables that are independent of the global
+// tables and functions for merging one table into another.
+
+package intern
+
+import "fmt"
+
+// An EqTable maps strings to Eqs independently of the global Eq table and of
+// every other EqTable.  Each Eq is meaningful only with respect to the table
+// that produced it, so Eqs from different tables must not be compared, and an
+// Eq from a table other than the global table must be converted back to a
+// string with the table's String method rather than with Eq.String.  An
+// EqTable is safe for concurrent use.
+type EqTable struct {
+	st *state
+}
+
+// NewEqTable returns a new, empty EqTable.
+func NewEqTable() *EqTable {
+	t := &EqTable{st: new(state)}
+	t.st.forgetAll()
+	return t
+}
+
+// DefaultEqTable returns an EqTable that refers to the global Eq table, as
+// used by NewEq, LookupEq, and Eq.String.
+func DefaultEqTable() *EqTable {
+	return &EqTable{st: &eq}
+}
+
+// NewEq maps a string to an Eq in an EqTable.  It panics if given a new
+// string after the table has been frozen.
+func (t *EqTable) NewEq(s string) Eq {
+	sym, err := t.st.newEq(s)
+	if err != nil {
+		panic(err)
+	}
+	return sym
+}
+
+// NewEqMulti performs the same operation as NewEq but accepts a slice of
+// strings instead of an individual string.
+func (t *EqTable) NewEqMulti(ss []string) []Eq {
+	eqs, err := t.st.newEqMulti(ss)
+	if err != nil {
+		panic(err)
+	}
+	return eqs
+}
+
+// Intern performs the same operation as NewEqMulti but returns an error
+// rather than panicking if the table is frozen and lacks any of the strings.
+func (t *EqTable) Intern(ss []string) ([]Eq, error) {
+	return t.st.newEqMulti(ss)
+}
+
+// LookupEq returns the Eq associated with a string in an EqTable without
+// allocating a new Eq.  The second return value indicates whether the string
+// was found.
+func (t *EqTable) LookupEq(s string) (Eq, bool) {
+	return t.st.lookupEq(s)
+}
+
+// String converts an Eq from an EqTable back to a string.  It panics if given
+// an Eq that was not created by the table.
+func (t *EqTable) String(e Eq) string {
+	return t.st.eqString(symbol(e))
+}
+
+// LookupString returns the string associated with an Eq in an EqTable.  Unlike
+// String, it does not panic if the Eq is unknown.  The second return value
+// indicates whether the Eq was found.
+func (t *EqTable) LookupString(e Eq) (string, bool) {
+	st := t.st
+	if symbol(e)&inlineTag != 0 && st.inline.Load() {
+		return inlineStr(symbol(e)), true
+	}
+	if ft := st.frozenTable(); ft != nil {
+		return ft.str(symbol(e))
+	}
+	st.RLock()
+	defer st.RUnlock()
+	s, ok := st.symToStr[symbol(e)]
+	return s, ok
+}
+
+// Len returns the number of strings in an EqTable.  Strings packed into inline
+// Eqs (see SetEqMode) are not counted.
+func (t *EqTable) Len() int {
+	return t.st.size()
+}
+
+// An LGETable maps strings to LGEs independently of the global LGE table and
+// of every other LGETable.  Each LGE is meaningful only with respect to the
+// table that produced it, so LGEs from different tables must not be compared,
+// and an LGE from a table other than the global table must be converted back
+// to a string with the table's String method rather than with LGE.String.  An
+// LGETable is safe for concurrent use.
+type LGETable struct {
+	st *state
+}
+
+// NewLGETable returns a new, empty LGETable.
+func NewLGETable() *LGETable {
+	t := &LGETable{st: new(state)}
+	t.st.forgetAll()
+	return t
+}
+
+// DefaultLGETable returns an LGETable that refers to the global LGE table, as
+// used by NewLGE, LookupLGE, and LGE.String.
+func DefaultLGETable() *LGETable {
+	return &LGETable{st: &lge}
+}
+
+// NewLGE maps a string to an LGE in an LGETable.  Like the package-level
+// NewLGE, it returns an error if the table cannot accommodate the string.
+func (t *LGETable) NewLGE(s string) (LGE, error) {
+	return t.st.newLGE(s)
+}
+
+// NewLGEMulti performs the same operation as NewLGE but accepts a slice of
+// strings instead of an individual string.  Allocating many LGEs at once
+// reduces the likelihood of running out of properly comparable LGEs.
+func (t *LGETable) NewLGEMulti(ss []string) ([]LGE, error) {
+	return t.st.newLGEMulti(ss)
+}
+
+// LookupLGE returns the LGE associated with a string in an LGETable without
+// allocating a new LGE.  The second return value indicates whether the string
+// was found.
+func (t *LGETable) LookupLGE(s string) (LGE, bool) {
+	return t.st.lookupLGE(s)
+}
+
+// String converts an LGE from an LGETable back to a string.  It panics if
+// given an LGE that was not created by the table.
+func (t *LGETable) String(l LGE) string {
+	return t.st.toString(symbol(l), "LGE")
+}
+
+// Len returns the number of strings in an LGETable.
+func (t *LGETable) Len() int {
+	return t.st.size()
+}
+
+// RemapAll reassigns an LGETable's LGEs to its strings, as RemapAllLGEs does
+// for the global table, and returns a mapping from old LGEs to new LGEs.
+func (t *LGETable) RemapAll() (map[LGE]LGE, error) {
+	return t.st.remapLGEs()
+}
+
+// size returns the number of strings in a state's table.
+func (st *state) size() int {
+	if ft := st.frozenTable(); ft != nil {
+		return ft.size()
+	}
+	st.RLock()
+	defer st.RUnlock()
+	return len(st.symToStr)
+}
+
+// contents returns all of a state's strings and their symbols in no
+// particular order.
+func (st *state) contents() ([]string, []symbol) {
+	st.RLock()
+	defer st.RUnlock()
+	var strs []string
+	var syms []symbol
+	st.eachSym(func(s string, sym symbol) {
+		strs = append(strs, s)
+		syms = append(syms, sym)
+	})
+	return strs, syms
+}
+
+// MergeEqTables interns every string in src into dst and returns a mapping
+// from each of src's Eqs to the Eq of the same string in dst.  Pass the
+// mapping to TranslateEqs, TranslateEqSet, or TranslateEqKeys to rewrite data
+// structures built against src so they can be used with dst.  MergeEqTables
+// returns an error if dst is frozen and lacks any of src's strings.
+//
+// Strings packed into inline Eqs (see SetEqMode) are not stored in src, so
+// MergeEqTables cannot enumerate them.  It therefore returns an error if src
+// is in EqInlineMode and dst is not, as dst would not recognize src's inline
+// Eqs.  If both are in EqInlineMode, inline Eqs mean the same thing in both
+// tables and are omitted from the mapping.
+func MergeEqTables(dst, src *EqTable) (map[Eq]Eq, error) {
+	if src.st.inline.Load() && !dst.st.inline.Load() {
+		return nil, &PkgError{
+			Code: ErrModeMismatch,
+			msg:  "Unable to merge an Eq table in EqInlineMode into one that is not",
+		}
+	}
+	strs, syms := src.st.contents()
+	eqs, err := dst.st.newEqMulti(strs)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[Eq]Eq, len(strs))
+	for i, sym := range syms {
+		m[Eq(sym)] = eqs[i]
+	}
+	return m, nil
+}
+
+// MergeLGETables interns every string in src into dst and returns a mapping
+// from each of src's LGEs to the LGE of the same string in dst.  Pass the
+// mapping to TranslateLGEs or TranslateLGEKeys to rewrite data structures
+// built against src so they can be used with dst.  MergeLGETables returns an
+// error if dst cannot accommodate all of src's strings, in which case calling
+// dst's RemapAll method and then merging again may succeed.
+func MergeLGETables(dst, src *LGETable) (map[LGE]LGE, error) {
+	strs, syms := src.st.contents()
+	lges, err := dst.st.newLGEMulti(strs)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[LGE]LGE, len(strs))
+	for i, sym := range syms {
+		m[LGE(sym)] = lges[i]
+	}
+	return m, nil
+}
+
+// unmappedEq returns an error if an Eq does not appear in a mapping such as
+// the one returned by MergeEqTables and is not an inline Eq, which the
+// mapping omits.
+func unmappedEq(e Eq, m map[Eq]Eq) error {
+	if _, ok := m[e]; ok || symbol(e)&inlineTag != 0 {
+		return nil
+	}
+	return &PkgError{
+		Code: ErrNotMapped,
+		msg:  fmt.Sprintf("Eq %d does not appear in the mapping", e),
+	}
+}
+
+// unmappedLGE returns an error if an LGE does not appear in a mapping such as
+// the one returned by MergeLGETables or RemapAllLGEs.
+func unmappedLGE(l LGE, m map[LGE]LGE) error {
+	if _, ok := m[l]; ok {
+		return nil
+	}
+	return &PkgError{
+		Code: ErrNotMapped,
+		msg:  fmt.Sprintf("LGE %d does not appear in the mapping", l),
+	}
+}
+
+// TranslateEqs replaces each Eq in a slice with its image under a mapping
+// such as the one returned by MergeEqTables.  Inline Eqs, which the mapping
+// omits, are left unchanged.  TranslateEqs returns an error, leaving the
+// slice unmodified, if any other Eq does not appear in the mapping.
+func TranslateEqs(eqs []Eq, m map[Eq]Eq) error {
+	for _, e := range eqs {
+		if err := unmappedEq(e, m); err != nil {
+			return err
+		}
+	}
+	for i, e := range eqs {
+		if e2, ok := m[e]; ok {
+			eqs[i] = e2
+		}
+	}
+	return nil
+}
+
+// TranslateEqSet returns a new EqSet containing the image of each Eq in an
+// EqSet under a mapping such as the one returned by MergeEqTables.  Like
+// TranslateEqs, it copies inline Eqs unchanged and returns an error if any
+// other Eq does not appear in the mapping.
+func TranslateEqSet(s *EqSet, m map[Eq]Eq) (*EqSet, error) {
+	eqs := s.Eqs()
+	if err := TranslateEqs(eqs, m); err != nil {
+		return nil, err
+	}
+	return NewEqSet(eqs...), nil
+}
+
+// TranslateEqKeys returns a new map in which each key of a given map is
+// replaced by its image under a mapping such as the one returned by
+// MergeEqTables.  Like TranslateEqs, it copies inline Eqs unchanged and
+// returns an error if any other key does not appear in the mapping.
+func TranslateEqKeys[V any](vals map[Eq]V, m map[Eq]Eq) (map[Eq]V, error) {
+	out := make(map[Eq]V, len(vals))
+	for e, v := range vals {
+		if err := unmappedEq(e, m); err != nil {
+			return nil, err
+		}
+		if e2, ok := m[e]; ok {
+			e = e2
+		}
+		out[e] = v
+	}
+	return out, nil
+}
+
+// TranslateLGEs replaces each LGE in a slice with its image under a mapping
+// such as the one returned by MergeLGETables or RemapAllLGEs.  Because both
+// tables order LGEs like their strings, a sorted slice of translated LGEs
+// remains sorted.  TranslateLGEs returns an error, leaving the slice
+// unmodified, if any LGE does not appear in the mapping.
+func TranslateLGEs(lges []LGE, m map[LGE]LGE) error {
+	for _, l := range lges {
+		if err := unmappedLGE(l, m); err != nil {
+			return err
+		}
+	}
+	for i, l := range lges {
+		lges[i] = m[l]
+	}
+	return nil
+}
+
+// TranslateLGEKeys returns a new map in which each key of a given map is
+// replaced by its image under a mapping such as the one returned by
+// MergeLGETables or RemapAllLGEs.  It returns an error if any key does not
+// appear in the mapping.
+func TranslateLGEKeys[V any](vals map[LGE]V, m map[LGE]LGE) (map[LGE]V, error) {
+	out := make(map[LGE]V, len(vals))
+	for l, v := range vals {
+		if err := unmappedLGE(l, m); err != nil {
+			return nil, err
+		}
+		out[m[l]] = v
+	}
+	return out, nil
+}
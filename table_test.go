@@ -0,0 +1,236 @@
+// This file provides unit tests for independent symbol tables and for
+// merging them.
+
+package intern_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/spakin/intern"
+)
+
+// TestEqTables ensures that independent Eq tables can be merged and that
+// data structures built against one can be translated to another.
+func TestEqTables(t *testing.T) {
+	// Intern overlapping halves of ozChars in two shards.
+	half := len(ozChars) / 2
+	shard1, shard2 := intern.NewEqTable(), intern.NewEqTable()
+	eqs1 := shard1.NewEqMulti(ozChars[:half+10])
+	eqs2 := shard2.NewEqMulti(ozChars[half:])
+	if n := shard1.Len(); n != half+10 {
+		t.Fatalf("Expected %d strings but saw %d", half+10, n)
+	}
+	counts := make(map[intern.Eq]int)
+	for i, e := range eqs2 {
+		counts[e] = i
+	}
+	set := intern.NewEqSet(eqs2...)
+
+	// Merge both shards into a fresh table.
+	dst := intern.NewEqTable()
+	m1, err := intern.MergeEqTables(dst, shard1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2, err := intern.MergeEqTables(dst, shard2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := dst.Len(); n != len(ozChars) {
+		t.Fatalf("Expected %d strings but saw %d", len(ozChars), n)
+	}
+	if err = intern.TranslateEqs(eqs1, m1); err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range eqs1 {
+		if s := dst.String(e); s != ozChars[i] {
+			t.Fatalf("Expected %q but saw %q", ozChars[i], s)
+		}
+	}
+	if counts, err = intern.TranslateEqKeys(counts, m2); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range ozChars[half:] {
+		e, ok := dst.LookupEq(s)
+		if !ok {
+			t.Fatalf("Failed to look up %q", s)
+		}
+		if counts[e] != i {
+			t.Fatalf("Expected %q to map to %d but saw %d", s, i, counts[e])
+		}
+	}
+	if set, err = intern.TranslateEqSet(set, m2); err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != len(eqs2) {
+		t.Fatalf("Expected %d Eqs but saw %d", len(eqs2), set.Len())
+	}
+	for _, s := range ozChars[half:] {
+		if !set.Contains(dst.NewEq(s)) {
+			t.Fatalf("Translated set lacks %q", s)
+		}
+	}
+
+	// Merging into the global table makes Eq.String work.
+	intern.ForgetAllEqs()
+	defer intern.ForgetAllEqs()
+	m, err := intern.MergeEqTables(intern.DefaultEqTable(), dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for e1, e2 := range m {
+		if e2.String() != dst.String(e1) {
+			t.Fatalf("Expected %q but saw %q", dst.String(e1), e2)
+		}
+	}
+}
+
+// TestEqTablesInline ensures that merging handles inline Eqs without passing
+// them through to a table that does not recognize them.
+func TestEqTablesInline(t *testing.T) {
+	useEqMode(t, intern.EqInlineMode)
+	defer useEqMode(t, intern.EqTableMode)
+	short := intern.NewEq("Ozma")
+	long := intern.NewEq("Dorothy Gale")
+
+	// A table that does not pack strings cannot accept inline Eqs.
+	dst := intern.NewEqTable()
+	_, err := intern.MergeEqTables(dst, intern.DefaultEqTable())
+	if pe, ok := err.(*intern.PkgError); !ok || pe.Code != intern.ErrModeMismatch {
+		t.Fatalf("Expected ErrModeMismatch but saw %v", err)
+	}
+	if dst.Len() != 0 {
+		t.Fatalf("Expected an empty table but saw %d strings", dst.Len())
+	}
+
+	// The reverse direction packs the short string as it is merged.
+	src := intern.NewEqTable()
+	eqs := src.NewEqMulti([]string{"Ozma", "Dorothy Gale"})
+	m, err := intern.MergeEqTables(intern.DefaultEqTable(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = intern.TranslateEqs(eqs, m); err != nil {
+		t.Fatal(err)
+	}
+	if eqs[0] != short || eqs[1] != long {
+		t.Fatalf("Expected Eqs %d and %d but saw %d and %d", short, long, eqs[0], eqs[1])
+	}
+}
+
+// TestLGETables ensures that independent LGE tables can be merged without
+// disturbing the order of translated LGEs.
+func TestLGETables(t *testing.T) {
+	strs := generateRandomStrings(2000)
+	shard1, shard2 := intern.NewLGETable(), intern.NewLGETable()
+	lges1, err := shard1.NewLGEMulti(strs[:1000])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = shard2.NewLGEMulti(strs[1000:]); err != nil {
+		t.Fatal(err)
+	}
+	dst := intern.NewLGETable()
+	m1, err := intern.MergeLGETables(dst, shard1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Merging a second shard may require remapping the first.
+	m2, err := intern.MergeLGETables(dst, shard2)
+	if err != nil {
+		remap, err := dst.RemapAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for l1, l2 := range m1 {
+			m1[l1] = remap[l2]
+		}
+		if m2, err = intern.MergeLGETables(dst, shard2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(m2) != shard2.Len() {
+		t.Fatalf("Expected %d translations but saw %d", shard2.Len(), len(m2))
+	}
+	if n, want := dst.Len(), len(dedupStrings(sortedCopy(strs))); n != want {
+		t.Fatalf("Expected %d strings but saw %d", want, n)
+	}
+
+	// Translated LGEs sort like their strings.
+	vals := make(map[intern.LGE]string, len(lges1))
+	for i, l := range lges1 {
+		vals[l] = strs[i]
+	}
+	sort.Slice(lges1, func(i, j int) bool { return lges1[i] < lges1[j] })
+	if err = intern.TranslateLGEs(lges1, m1); err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range lges1 {
+		if i > 0 && lges1[i-1] >= l {
+			t.Fatalf("LGE %d follows LGE %d", l, lges1[i-1])
+		}
+	}
+	if vals, err = intern.TranslateLGEKeys(vals, m1); err != nil {
+		t.Fatal(err)
+	}
+	for l, s := range vals {
+		if dst.String(l) != s {
+			t.Fatalf("Expected %q but saw %q", s, dst.String(l))
+		}
+		if l2, ok := dst.LookupLGE(s); !ok || l2 != l {
+			t.Fatalf("Failed to look up %q", s)
+		}
+	}
+}
+
+// sortedCopy returns a sorted copy of a slice of strings.
+func sortedCopy(ss []string) []string {
+	out := append([]string(nil), ss...)
+	sort.Strings(out)
+	return out
+}
+
+// TestTranslateUnmapped ensures that translating a symbol absent from a
+// mapping fails without modifying its input.
+func TestTranslateUnmapped(t *testing.T) {
+	src, other := intern.NewEqTable(), intern.NewEqTable()
+	eqs := src.NewEqMulti([]string{"Glinda", "Tip"})
+	stray := other.NewEqMulti([]string{"Glinda", "Tip", "Jack Pumpkinhead"})[2]
+	m, err := intern.MergeEqTables(intern.NewEqTable(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNotMapped := func(err error) {
+		t.Helper()
+		if pe, ok := err.(*intern.PkgError); !ok || pe.Code != intern.ErrNotMapped {
+			t.Fatalf("Expected ErrNotMapped but saw %v", err)
+		}
+	}
+	in := []intern.Eq{eqs[0], eqs[1], stray}
+	err = intern.TranslateEqs(in, m)
+	expectNotMapped(err)
+	if in[0] != eqs[0] || in[1] != eqs[1] {
+		t.Fatalf("Expected Eqs %v to be left unmodified but saw %v", eqs, in[:2])
+	}
+	_, err = intern.TranslateEqSet(intern.NewEqSet(in...), m)
+	expectNotMapped(err)
+	_, err = intern.TranslateEqKeys(map[intern.Eq]int{stray: 1}, m)
+	expectNotMapped(err)
+
+	// An LGE left over from before a remapping is also absent.
+	lsrc := intern.NewLGETable()
+	lges, err := lsrc.NewLGEMulti([]string{"Glinda", "Tip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lm, err := intern.MergeLGETables(intern.NewLGETable(), lsrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = intern.TranslateLGEs([]intern.LGE{lges[0], lges[1], lges[0] + 1}, lm)
+	expectNotMapped(err)
+	_, err = intern.TranslateLGEKeys(map[intern.LGE]int{lges[0] + 1: 1}, lm)
+	expectNotMapped(err)
+}
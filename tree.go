@@ -5,8 +5,13 @@ package intern
 import (
 	"fmt"
 	"sort"
+	"strings"
 )
 
+// treeRoot is the symbol assigned to the root of a tree that spans the entire
+// symbol space.
+const treeRoot symbol = 1 << 63
+
 // A tree represents a binary tree of strings.
 type tree struct {
 	str   string // Contents of this node
@@ -18,12 +23,21 @@ type tree struct {
 // insert inserts a string into a tree, returning the new tree, the inserted
 // symbol, and an error value.
 func (t *tree) insert(s string) (*tree, symbol, error) {
-	return t.insertHelper(s, 1<<63, 1<<62)
+	return t.insertAt(s, treeRoot)
+}
+
+// insertAt inserts a string into a tree whose root is assigned a given
+// symbol.  Each node's children lie half the distance to the node's lowest
+// set bit away from it, so the tree spans only the symbols that share the
+// root's bits above its lowest set bit.
+func (t *tree) insertAt(s string, root symbol) (*tree, symbol, error) {
+	return t.insertHelper(s, root, (root&-root)/2)
 }
 
 // insertHelper inserts a string into a tree, returning the new tree, the
 // inserted symbol, and an error value.  It performs almost all of the work for
-// the top-level insert method.
+// the top-level insert method.  On failure, the tree is returned unmodified
+// so that callers do not lose any of its existing nodes.
 func (t *tree) insertHelper(s string, val, incr symbol) (*tree, symbol, error) {
 	if t == nil {
 		return &tree{str: s, sym: val}, val, nil
@@ -34,7 +48,7 @@ func (t *tree) insertHelper(s string, val, incr symbol) (*tree, symbol, error) {
 			Str:  s,
 			msg:  fmt.Sprintf("Unable to insert %q; symbol table is full", s),
 		}
-		return nil, 0, e
+		return t, 0, e
 	}
 	var sym symbol
 	var err error
@@ -51,9 +65,9 @@ func (t *tree) insertHelper(s string, val, incr symbol) (*tree, symbol, error) {
 
 // insertMany inserts a list of strings into a tree, attempting to maintain
 // balance as it does so.  A new tree, a map from strings to symbols, and an
-// error value are returned.  It is assumed that the given list of strings is
-// non-empty.
-func (t *tree) insertMany(ss []string) (*tree, map[string]symbol, error) {
+// error value are returned.  The root of the tree is assigned symbol root.  It
+// is assumed that the given list of strings is non-empty.
+func (t *tree) insertMany(ss []string, root symbol) (*tree, map[string]symbol, error) {
 	// Create a sorted version of the list of strings.
 	sss := make([]string, len(ss))
 	for i, s := range ss {
@@ -63,7 +77,7 @@ func (t *tree) insertMany(ss []string) (*tree, map[string]symbol, error) {
 
 	// Call our helper function then construct a map based on the list of
 	// symbols it returns.
-	tNew, syms, err := t.insertManySorted(sss)
+	tNew, syms, err := t.insertManySorted(sss, root)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -78,30 +92,30 @@ func (t *tree) insertMany(ss []string) (*tree, map[string]symbol, error) {
 // insertManySorted inserts a sorted list of strings into a tree, attempting to
 // maintain balance as it does so.  It performs most of the work for
 // insertMany.  It is assumed that the given list of strings is non-empty.
-func (t *tree) insertManySorted(ss []string) (*tree, symbolList, error) {
+func (t *tree) insertManySorted(ss []string, root symbol) (*tree, symbolList, error) {
 	// Handle the base case (a single string) first.
 	n := len(ss)
 	if n == 1 {
-		tNew, s, err := t.insert(ss[0])
+		tNew, s, err := t.insertAt(ss[0], root)
 		return tNew, symbolList{s}, err
 	}
 
 	// Insert the middle element, then recursively insert the left and
 	// right sub-slices.
 	mid := n / 2
-	tNew, sym, err := t.insert(ss[mid])
+	tNew, sym, err := t.insertAt(ss[mid], root)
 	if err != nil {
 		return nil, nil, err
 	}
 	var lSyms, rSyms symbolList
 	if mid > 0 {
-		tNew, lSyms, err = tNew.insertManySorted(ss[:mid])
+		tNew, lSyms, err = tNew.insertManySorted(ss[:mid], root)
 		if err != nil {
 			return nil, nil, err
 		}
 	}
 	if mid+1 < n {
-		tNew, rSyms, err = tNew.insertManySorted(ss[mid+1:])
+		tNew, rSyms, err = tNew.insertManySorted(ss[mid+1:], root)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -110,3 +124,36 @@ func (t *tree) insertManySorted(ss []string) (*tree, symbolList, error) {
 	sList = append(sList, rSyms...)
 	return tNew, sList, nil
 }
+
+// clone returns a deep copy of a tree.
+func (t *tree) clone() *tree {
+	if t == nil {
+		return nil
+	}
+	return &tree{
+		str:   t.str,
+		sym:   t.sym,
+		left:  t.left.clone(),
+		right: t.right.clone(),
+	}
+}
+
+// eachPrefix calls a function on each string in a tree that begins with a
+// given prefix, along with its symbol, in sorted order.  It stops early, and
+// returns false, if the function returns false.
+func (t *tree) eachPrefix(prefix string, f func(s string, sym symbol) bool) bool {
+	if t == nil {
+		return true
+	}
+	match := strings.HasPrefix(t.str, prefix)
+	if (match || t.str > prefix) && !t.left.eachPrefix(prefix, f) {
+		return false
+	}
+	if match && !f(t.str, t.sym) {
+		return false
+	}
+	if match || t.str < prefix {
+		return t.right.eachPrefix(prefix, f)
+	}
+	return true
+}